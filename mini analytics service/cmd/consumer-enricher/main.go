@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -19,6 +20,7 @@ import (
 	ikafka "tiny-analytics/internal/kafka"
 	"tiny-analytics/internal/model"
 	"tiny-analytics/internal/pipeline"
+	"tiny-analytics/pkg/batcher"
 )
 
 var (
@@ -38,8 +40,26 @@ var (
 		Name: "enricher_consumer_lag",
 		Help: "Current consumer lag reported by kafka-go",
 	})
+	batchSizeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "enricher_batch_size",
+		Help:    "Histogram of enricher output batch sizes",
+		Buckets: []float64{1, 10, 50, 100, 250, 500, 1000},
+	})
+	batchFlushDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "enricher_batch_flush_duration_seconds",
+		Help:    "Duration of enricher batch flush operations (produce + commit)",
+		Buckets: prometheus.DefBuckets,
+	})
 )
 
+// enrichedItem pairs an enriched message ready to write to events.enriched
+// with the raw events.raw message it was derived from, so a flush can
+// commit offsets only once the write actually succeeds.
+type enrichedItem struct {
+	out kafkago.Message
+	src kafkago.Message
+}
+
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -48,22 +68,43 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	reader := ikafka.NewReader(cfg.KafkaBrokers, cfg.KafkaTopicRaw, "enricher-group")
-	writer := ikafka.NewWriter(cfg.KafkaBrokers, cfg.KafkaTopicEnriched)
+	reader, err := ikafka.NewReader(cfg.KafkaBrokers, cfg.KafkaTopicRaw, "enricher-group", cfg.KafkaSecurity.ToKafkaOptions())
+	if err != nil {
+		log.Fatalf("build kafka reader: %v", err)
+	}
+	writer, err := ikafka.NewWriter(cfg.KafkaBrokers, cfg.KafkaTopicEnriched, cfg.KafkaSecurity.ToKafkaOptions())
+	if err != nil {
+		log.Fatalf("build kafka writer: %v", err)
+	}
 	defer reader.Close()
 	defer writer.Close()
 
+	geo, err := newGeoResolver(cfg)
+	if err != nil {
+		log.Fatalf("geoip: %v", err)
+	}
+	if closer, ok := geo.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	flusher := flushFunc(ctx, reader, writer)
+	b, err := batcher.New[enrichedItem](cfg.EnricherBatchSize, cfg.EnricherBatchInterval, flusher)
+	if err != nil {
+		log.Fatalf("batcher: %v", err)
+	}
+	defer b.Close()
+
 	go serveMetrics(cfg.EnricherMetricsAddr)
 	go handleSignals(cancel)
 
 	for {
-		m, err := reader.ReadMessage(ctx)
+		m, err := reader.FetchMessage(ctx)
 		if err != nil {
 			if ctx.Err() != nil {
 				break
 			}
 			errorsTotal.Inc()
-			log.Printf("read kafka: %v", err)
+			log.Printf("fetch kafka: %v", err)
 			time.Sleep(time.Second)
 			continue
 		}
@@ -78,7 +119,7 @@ func main() {
 			continue
 		}
 
-		enriched, err := pipeline.Enrich(raw, cfg.IPHashSalt)
+		enriched, err := pipeline.Enrich(raw, cfg.IPHashSalt, geo)
 		if err != nil {
 			errorsTotal.Inc()
 			log.Printf("enrich event: %v", err)
@@ -90,20 +131,82 @@ func main() {
 			log.Printf("marshal enriched event: %v", err)
 			continue
 		}
+
+		if err := b.Add(enrichedItem{
+			out: kafkago.Message{Key: m.Key, Value: payload},
+			src: m,
+		}); err != nil {
+			errorsTotal.Inc()
+			log.Printf("batch add failed: %v", err)
+		}
+	}
+	log.Println("enricher shutdown complete")
+}
+
+// flushFunc returns the batcher flush callback: it writes every enriched
+// message in one batched produce call, and only commits the corresponding
+// raw-topic offsets back to the reader once that write succeeds, so a
+// failed flush leaves the messages uncommitted for at-least-once redelivery.
+func flushFunc(ctx context.Context, reader *kafkago.Reader, writer *kafkago.Writer) func([]enrichedItem) error {
+	return func(items []enrichedItem) error {
+		if len(items) == 0 {
+			return nil
+		}
+		start := time.Now()
+
+		outs := make([]kafkago.Message, len(items))
+		srcs := make([]kafkago.Message, len(items))
+		for i, item := range items {
+			outs[i] = item.out
+			srcs[i] = item.src
+		}
+
 		writeCtx, cancelWrite := context.WithTimeout(ctx, 10*time.Second)
-		err = writer.WriteMessages(writeCtx, kafkago.Message{
-			Key:   m.Key,
-			Value: payload,
-		})
+		err := writer.WriteMessages(writeCtx, outs...)
 		cancelWrite()
 		if err != nil {
-			errorsTotal.Inc()
-			log.Printf("produce enriched event: %v", err)
-			continue
+			return err
+		}
+		msgsProduced.Add(float64(len(outs)))
+
+		commitCtx, cancelCommit := context.WithTimeout(ctx, 10*time.Second)
+		err = reader.CommitMessages(commitCtx, latestPerPartition(srcs)...)
+		cancelCommit()
+		if err != nil {
+			return err
 		}
-		msgsProduced.Inc()
+
+		batchSizeHistogram.Observe(float64(len(items)))
+		batchFlushDuration.Observe(time.Since(start).Seconds())
+		return nil
 	}
-	log.Println("enricher shutdown complete")
+}
+
+// latestPerPartition collapses msgs down to the highest-offset message per
+// partition, since committing one offset per partition also commits every
+// earlier offset in that partition.
+func latestPerPartition(msgs []kafkago.Message) []kafkago.Message {
+	latest := make(map[int]kafkago.Message, len(msgs))
+	for _, m := range msgs {
+		if current, ok := latest[m.Partition]; !ok || m.Offset > current.Offset {
+			latest[m.Partition] = m
+		}
+	}
+	out := make([]kafkago.Message, 0, len(latest))
+	for _, m := range latest {
+		out = append(out, m)
+	}
+	return out
+}
+
+// newGeoResolver builds the GeoResolver the enricher enriches events with.
+// It returns a NoopGeoResolver when GeoIPDBPath is unset, so GeoIP support
+// remains opt-in and the enricher doesn't require the mmdb files in dev.
+func newGeoResolver(cfg config.Config) (pipeline.GeoResolver, error) {
+	if cfg.GeoIPDBPath == "" {
+		return pipeline.NoopGeoResolver{}, nil
+	}
+	return pipeline.NewMaxMindGeoResolver(cfg.GeoIPDBPath, cfg.GeoIPASNDBPath, 30*time.Second)
 }
 
 func handleSignals(cancel context.CancelFunc) {
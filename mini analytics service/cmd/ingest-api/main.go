@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -13,22 +14,40 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	kafkago "github.com/segmentio/kafka-go"
 
 	"tiny-analytics/internal/auth"
+	"tiny-analytics/internal/auth/noncecache"
 	"tiny-analytics/internal/config"
 	"tiny-analytics/internal/httpx"
 	ikafka "tiny-analytics/internal/kafka"
 	"tiny-analytics/internal/model"
 	"tiny-analytics/internal/util"
+	"tiny-analytics/pkg/ingestfmt"
 )
 
 const (
 	apiKeyHeader      = "X-TA-API-Key"
-	signatureHeader   = "X-TA-Signature"
 	unknownSiteError  = "unknown site"
 	missingKeyMessage = "missing or invalid api key"
+
+	nonceCacheTTL           = 10 * time.Minute
+	nonceCacheMaxSize       = 200_000
+	nonceCacheSweepInterval = time.Minute
+)
+
+var (
+	ga4Decoder      = ingestfmt.GA4Decoder{}
+	otlpLogsDecoder = ingestfmt.OTLPLogsDecoder{}
+
+	ingestFormatEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingest_format_events_total",
+		Help: "Total events accepted or rejected through the pluggable ingest format decoders",
+	}, []string{"format", "status"})
 )
 
 func main() {
@@ -37,21 +56,41 @@ func main() {
 		log.Fatalf("load config: %v", err)
 	}
 	log.Printf("starting ingest API on %s", cfg.IngestAddr)
-	writer := ikafka.NewWriter(cfg.KafkaBrokers, cfg.KafkaTopicRaw)
+	writer, err := ikafka.NewWriter(cfg.KafkaBrokers, cfg.KafkaTopicRaw, cfg.KafkaSecurity.ToKafkaOptions())
+	if err != nil {
+		log.Fatalf("build kafka writer: %v", err)
+	}
 	defer writer.Close()
 
+	var nonces auth.NonceSeen
+	if cfg.NonceRedisAddr != "" {
+		log.Printf("using Redis-backed nonce cache at %s", cfg.NonceRedisAddr)
+		nonces = noncecache.NewRedisCache(redis.NewClient(&redis.Options{Addr: cfg.NonceRedisAddr}), nonceCacheTTL)
+	} else {
+		inProcess := noncecache.New(nonceCacheTTL, nonceCacheMaxSize, nonceCacheSweepInterval)
+		defer inProcess.Close()
+		nonces = inProcess
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(httpx.NewHTTPMetrics("ingest_api").Handler())
 	router.Use(httpx.CORSMiddleware(cfg.CORSAllowOrigins))
+	router.Use(httpx.DecompressMiddleware(cfg.MaxBodyBytes))
 
 	router.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	router.POST("/v1/collect", func(c *gin.Context) {
-		handleCollect(c, cfg, writer)
+		handleCollect(c, cfg, writer, nonces)
+	})
+	router.POST("/mp/collect", func(c *gin.Context) {
+		handleGA4Collect(c, cfg, writer, ga4Decoder)
+	})
+	router.POST("/v1/logs", func(c *gin.Context) {
+		handleOTLPLogs(c, cfg, writer, otlpLogsDecoder)
 	})
 
 	server := &http.Server{
@@ -68,7 +107,7 @@ func main() {
 	graceful(server)
 }
 
-func handleCollect(c *gin.Context, cfg config.Config, writer *kafkago.Writer) {
+func handleCollect(c *gin.Context, cfg config.Config, writer *kafkago.Writer, nonces auth.NonceSeen) {
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
@@ -100,10 +139,34 @@ func handleCollect(c *gin.Context, cfg config.Config, writer *kafkago.Writer) {
 		secret = cfg.HMACSecret
 	}
 	if secret != "" {
-		sig := c.GetHeader(signatureHeader)
-		if sig == "" || !auth.VerifySignature(secret, body, sig) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
-			return
+		hasSignedHeaders := c.GetHeader(auth.TimestampHeader) != "" && c.GetHeader(auth.NonceHeader) != ""
+		if !hasSignedHeaders && !siteCred.RequireSignedTimestamp {
+			// Legacy v1 clients: fall back to body-only verification until
+			// the site migrates to require_signed_timestamp.
+			if !auth.VerifySignatureLegacy(secret, body, c.GetHeader(auth.SignatureHeader)) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+				return
+			}
+		} else {
+			skew := cfg.SignatureSkew
+			if skew <= 0 {
+				skew = 5 * time.Minute
+			}
+			reqCfg := auth.RequestConfig{SiteID: evt.SiteID, Secret: secret, Skew: skew, Nonces: nonces}
+			switch err := auth.VerifyRequest(c.Request.Header, body, reqCfg); {
+			case errors.Is(err, auth.ErrMissingHeaders):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "missing timestamp, nonce, or signature"})
+				return
+			case errors.Is(err, auth.ErrTimestampExpired):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "expired"})
+				return
+			case errors.Is(err, auth.ErrNonceReplayed):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "replayed"})
+				return
+			case errors.Is(err, auth.ErrInvalidSignature):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+				return
+			}
 		}
 	}
 
@@ -142,6 +205,137 @@ func handleCollect(c *gin.Context, cfg config.Config, writer *kafkago.Writer) {
 	c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
 }
 
+// handleGA4Collect accepts a GA4 Measurement Protocol body at
+// POST /mp/collect?measurement_id=...&api_secret=..., resolving the site by
+// measurement_id and authenticating api_secret against its APIKey, then
+// publishing the decoded events the same way handleCollect does.
+func handleGA4Collect(c *gin.Context, cfg config.Config, writer *kafkago.Writer, decoder ingestfmt.GA4Decoder) {
+	measurementID := c.Query("measurement_id")
+	siteID, siteCred, ok := cfg.SiteByGA4MeasurementID(measurementID)
+	if !ok {
+		ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": unknownSiteError})
+		return
+	}
+	apiSecret := c.Query("api_secret")
+	if apiSecret == "" || apiSecret != siteCred.APIKey {
+		ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": missingKeyMessage})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	events, err := decoder.Decode(body)
+	if err != nil {
+		ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	for i := range events {
+		events[i].SiteID = siteID
+	}
+
+	publishEvents(c, cfg, writer, decoder.Name(), events)
+}
+
+// handleOTLPLogs accepts an OTLP/HTTP logs export at POST /v1/logs,
+// authenticated the same way /v1/collect is (X-TA-API-Key against the site
+// resolved from the payload's ta.site_id resource attribute), then
+// publishes the decoded events.
+func handleOTLPLogs(c *gin.Context, cfg config.Config, writer *kafkago.Writer, decoder ingestfmt.OTLPLogsDecoder) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	events, err := decoder.Decode(body)
+	if err != nil {
+		ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	siteID := events[0].SiteID
+	siteCred, ok := cfg.Sites[siteID]
+	if !ok {
+		ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": unknownSiteError})
+		return
+	}
+	apiKey := c.GetHeader(apiKeyHeader)
+	if apiKey == "" || apiKey != siteCred.APIKey {
+		ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": missingKeyMessage})
+		return
+	}
+
+	// An ExportLogsServiceRequest can carry multiple ResourceLogs blocks,
+	// each with its own ta.site_id; only the first one was authenticated
+	// above, so reject the whole batch if any other resource claims a
+	// different site rather than publishing its events unauthenticated.
+	for _, evt := range events {
+		if evt.SiteID != siteID {
+			ingestFormatEvents.WithLabelValues(decoder.Name(), "rejected").Inc()
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "all resources must share the authenticated site_id"})
+			return
+		}
+	}
+
+	publishEvents(c, cfg, writer, decoder.Name(), events)
+}
+
+// publishEvents writes each decoded event to the raw Kafka topic, the same
+// way handleCollect does for the native /v1/collect payload, and responds
+// once every event has been queued (or the first failure is hit).
+func publishEvents(c *gin.Context, cfg config.Config, writer *kafkago.Writer, format string, events []model.Event) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	for _, evt := range events {
+		if evt.Props == nil {
+			evt.Props = map[string]any{}
+		}
+		if evt.TS == 0 {
+			evt.TS = time.Now().UnixMilli()
+		}
+		evt.IP = c.ClientIP()
+		evt.UA = c.GetHeader("User-Agent")
+
+		if util.IsBot(evt.UA, cfg.BotUserAgents) {
+			ingestFormatEvents.WithLabelValues(format, "ignored").Inc()
+			continue
+		}
+
+		raw := model.NewRawEvent(evt, evt.IP, evt.UA)
+		payload, err := json.Marshal(raw)
+		if err != nil {
+			log.Printf("marshal raw event: %v", err)
+			ingestFormatEvents.WithLabelValues(format, "error").Inc()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode event"})
+			return
+		}
+		if err := writer.WriteMessages(ctx, kafkago.Message{
+			Key:   []byte(evt.SiteID),
+			Value: payload,
+		}); err != nil {
+			log.Printf("write kafka: %v", err)
+			ingestFormatEvents.WithLabelValues(format, "error").Inc()
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "queue unavailable"})
+			return
+		}
+		ingestFormatEvents.WithLabelValues(format, "queued").Inc()
+	}
+	c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+}
+
 func graceful(server *http.Server) {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
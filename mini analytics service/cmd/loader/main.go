@@ -36,6 +36,10 @@ var (
 		Name: "loader_insert_errors_total",
 		Help: "Total ClickHouse insert failures",
 	})
+	deadLettered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "loader_deadlettered_batches_total",
+		Help: "Total batches written to the dead-letter directory after exhausting insert retries",
+	})
 )
 
 func main() {
@@ -55,20 +59,30 @@ func main() {
 		log.Fatalf("ensure schema: %v", err)
 	}
 
-	reader := ikafka.NewReader(cfg.KafkaBrokers, cfg.KafkaTopicEnriched, "loader-group")
+	reader, err := ikafka.NewReader(cfg.KafkaBrokers, cfg.KafkaTopicEnriched, "loader-group", cfg.KafkaSecurity.ToKafkaOptions())
+	if err != nil {
+		log.Fatalf("build kafka reader: %v", err)
+	}
 	defer reader.Close()
 
 	flusher := func(events []model.EnrichedEvent) error {
-		return insertWithRetry(ctx, client, events)
+		return insertWithRetry(ctx, client, cfg.LoaderDeadLetterDir, events)
+	}
+	var opts []batcher.Option[model.EnrichedEvent]
+	if cfg.LoaderWALDir != "" {
+		opts = append(opts, batcher.WithWAL[model.EnrichedEvent](cfg.LoaderWALDir, cfg.LoaderWALMaxBytes))
+	}
+	b, err := batcher.New[model.EnrichedEvent](cfg.BatchSize, cfg.BatchInterval, flusher, opts...)
+	if err != nil {
+		log.Fatalf("batcher: %v", err)
 	}
-	b := batcher.New[model.EnrichedEvent](cfg.BatchSize, cfg.BatchInterval, flusher)
 	defer b.Close()
 
 	go serveMetrics(cfg.LoaderMetricsAddr)
 	go handleSignals(cancel)
 
 	for {
-		m, err := reader.ReadMessage(ctx)
+		m, err := reader.FetchMessage(ctx)
 		if err != nil {
 			if ctx.Err() != nil {
 				break
@@ -82,17 +96,26 @@ func main() {
 			log.Printf("decode enriched event: %v", err)
 			continue
 		}
+		// Add only returns once the event is durably in the WAL (when
+		// configured), so it's safe to commit the offset right after: a
+		// crash before the batch reaches ClickHouse replays from the WAL
+		// instead of needing Kafka redelivery.
 		if err := b.Add(evt); err != nil {
 			log.Printf("batch add failed: %v", err)
+			continue
+		}
+		if err := reader.CommitMessages(ctx, m); err != nil {
+			log.Printf("commit enriched offset: %v", err)
 		}
 	}
 	log.Println("loader shutdown complete")
 }
 
-func insertWithRetry(ctx context.Context, client *ch.Client, events []model.EnrichedEvent) error {
+func insertWithRetry(ctx context.Context, client *ch.Client, deadLetterDir string, events []model.EnrichedEvent) error {
 	const maxAttempts = 5
 	backoff := 200 * time.Millisecond
 	start := time.Now()
+	var lastErr error
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		insertCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 		err := client.InsertBatch(insertCtx, events)
@@ -102,9 +125,10 @@ func insertWithRetry(ctx context.Context, client *ch.Client, events []model.Enri
 			batchSizeHistogram.Observe(float64(len(events)))
 			return nil
 		}
+		lastErr = err
 		insertErrors.Inc()
 		if attempt == maxAttempts {
-			return err
+			break
 		}
 		select {
 		case <-ctx.Done():
@@ -116,6 +140,18 @@ func insertWithRetry(ctx context.Context, client *ch.Client, events []model.Enri
 			backoff = 5 * time.Second
 		}
 	}
+
+	// Retries are exhausted. Rather than silently dropping the batch,
+	// persist it so an operator can inspect or replay it with wal-tool;
+	// that also lets the caller's WAL segment be truncated, since the
+	// batch is now durable in the dead-letter file instead.
+	path, dlErr := batcher.WriteDeadLetter(deadLetterDir, events, lastErr)
+	if dlErr != nil {
+		log.Printf("dead-letter batch: %v (original insert error: %v)", dlErr, lastErr)
+		return lastErr
+	}
+	deadLettered.Inc()
+	log.Printf("dead-lettered %d events to %s after insert error: %v", len(events), path, lastErr)
 	return nil
 }
 
@@ -50,6 +50,15 @@ func main() {
 	router.GET("/v1/metrics/top-pages", func(c *gin.Context) {
 		handleTopPages(c, client)
 	})
+	router.GET("/v1/metrics/top-countries", func(c *gin.Context) {
+		handleTopCountries(c, client)
+	})
+	router.GET("/v1/metrics/top-browsers", func(c *gin.Context) {
+		handleTopBrowsers(c, client)
+	})
+	router.GET("/v1/metrics/top-os", func(c *gin.Context) {
+		handleTopOS(c, client)
+	})
 
 	server := &http.Server{
 		Addr:    cfg.QueryAddr,
@@ -92,12 +101,13 @@ func handleTimeseries(c *gin.Context, client *ch.Client, metric string) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
+	raw := c.Query("raw") == "true"
 	var series []ch.MetricPoint
 	switch metric {
 	case "pageviews":
-		series, err = client.Pageviews(ctx, siteID, from, to)
+		series, err = client.Pageviews(ctx, siteID, from, to, raw)
 	case "unique-users":
-		series, err = client.UniqueUsers(ctx, siteID, from, to)
+		series, err = client.UniqueUsers(ctx, siteID, from, to, raw)
 	default:
 		c.JSON(http.StatusNotFound, gin.H{"error": "unknown metric"})
 		return
@@ -148,7 +158,8 @@ func handleTopPages(c *gin.Context, client *ch.Client) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	pages, err := client.TopPages(ctx, siteID, from, to, limit)
+	raw := c.Query("raw") == "true"
+	pages, err := client.TopPages(ctx, siteID, from, to, limit, raw)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
@@ -167,6 +178,147 @@ func handleTopPages(c *gin.Context, client *ch.Client) {
 	c.JSON(http.StatusOK, resp)
 }
 
+func handleTopCountries(c *gin.Context, client *ch.Client) {
+	siteID := c.Query("site_id")
+	fromStr := c.Query("from")
+	toStr := c.Query("to")
+	limitStr := c.DefaultQuery("limit", "20")
+	if siteID == "" || fromStr == "" || toStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "site_id, from, and to are required"})
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be positive"})
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	from, err := time.Parse("2006-01-02", fromStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", toStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	countries, err := client.TopCountries(ctx, siteID, from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	resp := gin.H{
+		"site_id":   siteID,
+		"from":      fromStr,
+		"to":        toStr,
+		"countries": countries,
+	}
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(http.StatusOK, resp)
+}
+
+func handleTopBrowsers(c *gin.Context, client *ch.Client) {
+	siteID := c.Query("site_id")
+	fromStr := c.Query("from")
+	toStr := c.Query("to")
+	limitStr := c.DefaultQuery("limit", "20")
+	if siteID == "" || fromStr == "" || toStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "site_id, from, and to are required"})
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be positive"})
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	from, err := time.Parse("2006-01-02", fromStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", toStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	browsers, err := client.TopBrowsers(ctx, siteID, from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	resp := gin.H{
+		"site_id":  siteID,
+		"from":     fromStr,
+		"to":       toStr,
+		"browsers": browsers,
+	}
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(http.StatusOK, resp)
+}
+
+func handleTopOS(c *gin.Context, client *ch.Client) {
+	siteID := c.Query("site_id")
+	fromStr := c.Query("from")
+	toStr := c.Query("to")
+	limitStr := c.DefaultQuery("limit", "20")
+	if siteID == "" || fromStr == "" || toStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "site_id, from, and to are required"})
+		return
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be positive"})
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	from, err := time.Parse("2006-01-02", fromStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", toStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	osList, err := client.TopOS(ctx, siteID, from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	resp := gin.H{
+		"site_id": siteID,
+		"from":    fromStr,
+		"to":      toStr,
+		"os":      osList,
+	}
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(http.StatusOK, resp)
+}
+
 func toAPIseries(points []ch.MetricPoint) []gin.H {
 	result := make([]gin.H, 0, len(points))
 	for _, p := range points {
@@ -0,0 +1,128 @@
+// Command wal-tool inspects and replays the loader's dead-letter batches:
+// events that exhausted insertWithRetry's attempts and were persisted to
+// disk (see batcher.WriteDeadLetter) instead of being silently dropped.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"tiny-analytics/internal/ch"
+	"tiny-analytics/internal/config"
+	"tiny-analytics/internal/model"
+	"tiny-analytics/pkg/batcher"
+)
+
+func main() {
+	dir := flag.String("dir", "data/loader-deadletter", "dead-letter directory to operate on")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: wal-tool [-dir DIR] <command>\n\nCommands:\n  list             list dead-letter files with their cause and batch size\n  dump <file>      print one dead-letter file's events as JSON\n  replay <file>    re-insert one dead-letter file's events into ClickHouse,\n                   removing the file on success\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch args[0] {
+	case "list":
+		err = runList(*dir)
+	case "dump":
+		if len(args) != 2 {
+			err = fmt.Errorf("dump requires a file name")
+			break
+		}
+		err = runDump(*dir, args[1])
+	case "replay":
+		if len(args) != 2 {
+			err = fmt.Errorf("replay requires a file name")
+			break
+		}
+		err = runReplay(*dir, args[1])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runList(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("(no dead-letter directory yet)")
+			return nil
+		}
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		record, err := batcher.ReadDeadLetter[model.EnrichedEvent](filepath.Join(dir, name))
+		if err != nil {
+			fmt.Printf("%s\t(unreadable: %v)\n", name, err)
+			continue
+		}
+		fmt.Printf("%s\t%d events\t%s\t%s\n", name, len(record.Batch), record.FailedAt.Format("2006-01-02T15:04:05Z07:00"), record.Cause)
+	}
+	return nil
+}
+
+func runDump(dir, name string) error {
+	record, err := batcher.ReadDeadLetter[model.EnrichedEvent](filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, evt := range record.Batch {
+		if err := enc.Encode(evt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runReplay(dir, name string) error {
+	path := filepath.Join(dir, name)
+	record, err := batcher.ReadDeadLetter[model.EnrichedEvent](path)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	ctx := context.Background()
+	client, err := ch.New(ctx, cfg.ClickHouseDSN)
+	if err != nil {
+		return fmt.Errorf("clickhouse: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.InsertBatch(ctx, record.Batch); err != nil {
+		return fmt.Errorf("replay insert: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("insert succeeded but failed to remove %s: %w", path, err)
+	}
+	fmt.Printf("replayed %d events from %s\n", len(record.Batch), name)
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"tiny-analytics/internal/config"
+	ikafka "tiny-analytics/internal/kafka"
+	"tiny-analytics/internal/model"
+	"tiny-analytics/internal/webhooks"
+)
+
+const dlqScanTimeout = 30 * time.Second
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader, err := ikafka.NewReader(cfg.KafkaBrokers, cfg.KafkaTopicEnriched, "webhook-dispatcher-group", cfg.KafkaSecurity.ToKafkaOptions())
+	if err != nil {
+		log.Fatalf("build kafka reader: %v", err)
+	}
+	defer reader.Close()
+	dlqWriter, err := ikafka.NewWriter(cfg.KafkaBrokers, cfg.KafkaTopicWebhookDLQ, cfg.KafkaSecurity.ToKafkaOptions())
+	if err != nil {
+		log.Fatalf("build kafka dlq writer: %v", err)
+	}
+	defer dlqWriter.Close()
+
+	dispatcher := webhooks.New(cfg.Sites, dlqWriter)
+
+	go serveAdmin(cfg, dispatcher)
+	go handleSignals(cancel)
+
+	for {
+		m, err := reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("read enriched message: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		var evt model.EnrichedEvent
+		if err := json.Unmarshal(m.Value, &evt); err != nil {
+			log.Printf("decode enriched event: %v", err)
+			continue
+		}
+		dispatcher.Dispatch(ctx, evt, m.Value)
+	}
+	log.Println("webhook dispatcher shutdown complete")
+}
+
+func serveAdmin(cfg config.Config, dispatcher *webhooks.Dispatcher) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/admin/replay", func(w http.ResponseWriter, r *http.Request) {
+		deliveryID := r.URL.Query().Get("delivery_id")
+		if deliveryID == "" {
+			http.Error(w, "delivery_id is required", http.StatusBadRequest)
+			return
+		}
+		err := dispatcher.ReplayFromDLQ(r.Context(), cfg.KafkaBrokers, cfg.KafkaTopicWebhookDLQ, deliveryID, dlqScanTimeout)
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("replayed"))
+		case errors.Is(err, webhooks.ErrDeliveryNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	server := &http.Server{Addr: cfg.WebhookAddr, Handler: mux}
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("webhook dispatcher admin server failed: %v", err)
+	}
+}
+
+func handleSignals(cancel context.CancelFunc) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCh
+	cancel()
+}
@@ -0,0 +1,114 @@
+// Package noncecache provides a bounded, TTL-based store for rejecting
+// replayed (site, nonce) pairs on signed ingest requests.
+package noncecache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var replaysRejected = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "auth_nonce_replays_rejected_total",
+	Help: "Total requests rejected because their (site, nonce) pair was already seen",
+})
+
+type entry struct {
+	expiresAt time.Time
+}
+
+// Cache is a bounded in-memory store of recently seen (siteID, nonce) pairs.
+// It is safe for concurrent use.
+type Cache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	maxSize  int
+	seen     map[string]entry
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New creates a Cache that remembers nonces for ttl and evicts the oldest
+// entries once maxSize is exceeded. A background sweeper runs every
+// sweepInterval to drop expired entries.
+func New(ttl time.Duration, maxSize int, sweepInterval time.Duration) *Cache {
+	c := &Cache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		seen:    make(map[string]entry),
+		stop:    make(chan struct{}),
+	}
+	go c.sweepLoop(sweepInterval)
+	return c
+}
+
+// SeenOrRemember reports whether (siteID, nonce) was already recorded. If
+// it was not seen before, it is remembered and false is returned. If it was
+// already seen, true is returned and a replay counter is incremented.
+func (c *Cache) SeenOrRemember(siteID, nonce string, now time.Time) bool {
+	key := siteID + ":" + nonce
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.seen[key]; ok && now.Before(e.expiresAt) {
+		replaysRejected.Inc()
+		return true
+	}
+
+	if len(c.seen) >= c.maxSize {
+		c.evictExpiredLocked(now)
+	}
+	if len(c.seen) >= c.maxSize {
+		c.evictOldestLocked()
+	}
+	c.seen[key] = entry{expiresAt: now.Add(c.ttl)}
+	return false
+}
+
+// Close stops the background sweeper.
+func (c *Cache) Close() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+func (c *Cache) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			c.evictExpiredLocked(time.Now())
+			c.mu.Unlock()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *Cache) evictExpiredLocked(now time.Time) {
+	for key, e := range c.seen {
+		if !now.Before(e.expiresAt) {
+			delete(c.seen, key)
+		}
+	}
+}
+
+// evictOldestLocked drops a single entry when the cache is still full after
+// an expiry sweep, preferring the one closest to expiry.
+func (c *Cache) evictOldestLocked() {
+	var oldestKey string
+	var oldestExpiry time.Time
+	for key, e := range c.seen {
+		if oldestKey == "" || e.expiresAt.Before(oldestExpiry) {
+			oldestKey = key
+			oldestExpiry = e.expiresAt
+		}
+	}
+	if oldestKey != "" {
+		delete(c.seen, oldestKey)
+	}
+}
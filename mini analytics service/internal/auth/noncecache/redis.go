@@ -0,0 +1,52 @@
+package noncecache
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+var redisErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "auth_nonce_redis_errors_total",
+	Help: "Total Redis errors encountered while recording a nonce, treated as fail-open",
+})
+
+// RedisCache is a Redis-backed NonceSeen store, for deployments that run
+// multiple ingest-api replicas behind a load balancer and need a shared view
+// of recently seen nonces instead of per-process Cache. Keys are
+// "siteID:nonce" with a TTL so the set self-prunes.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisCache wraps client with the NonceSeen interface, remembering each
+// nonce for ttl.
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+// SeenOrRemember reports whether (siteID, nonce) was already recorded,
+// atomically recording it via SETNX if not. A Redis error is treated as
+// fail-open (nonce reported as not-seen) so a transient Redis outage
+// degrades to no replay protection rather than rejecting every request;
+// the error is counted via auth_nonce_redis_errors_total for alerting.
+func (r *RedisCache) SeenOrRemember(siteID, nonce string, now time.Time) bool {
+	key := siteID + ":" + nonce
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	ok, err := r.client.SetNX(ctx, key, now.Unix(), r.ttl).Result()
+	if err != nil {
+		redisErrorsTotal.Inc()
+		return false
+	}
+	if ok {
+		return false
+	}
+	replaysRejected.Inc()
+	return true
+}
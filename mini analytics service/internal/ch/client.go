@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	_ "github.com/ClickHouse/clickhouse-go/v2"
@@ -57,9 +58,15 @@ CREATE TABLE IF NOT EXISTS events
   utm_medium       LowCardinality(String),
   utm_campaign     LowCardinality(String),
   country          LowCardinality(String),
+  region           LowCardinality(String),
+  city             String,
+  asn              LowCardinality(String),
   device_type      LowCardinality(String),
+  device_brand     LowCardinality(String),
   browser          LowCardinality(String),
+  browser_version  LowCardinality(String),
   os               LowCardinality(String),
+  os_version       LowCardinality(String),
   ip_hash          FixedString(64),
   payload          JSON,
   _ingested_at     DateTime64(3, 'UTC')
@@ -67,7 +74,83 @@ CREATE TABLE IF NOT EXISTS events
 ENGINE = MergeTree
 PARTITION BY toYYYYMM(event_date)
 ORDER BY (site_id, event_date, user_id, event_name, event_time)`
-	_, err := c.db.ExecContext(ctx, ddl)
+	if _, err := c.db.ExecContext(ctx, ddl); err != nil {
+		return err
+	}
+
+	// events_daily_site and events_daily_url are AggregatingMergeTree
+	// rollups kept current by materialized views that fire on every insert
+	// into events. Client query methods read from these tables instead of
+	// scanning raw events once a day's data is no longer "today" (see
+	// rollupWindow), falling back to the raw table for same-day reads and
+	// when the caller passes raw=true.
+	const rollupSiteDDL = `
+CREATE TABLE IF NOT EXISTS events_daily_site
+(
+  site_id      LowCardinality(String),
+  event_date   Date,
+  event_name   LowCardinality(String),
+  device_type  LowCardinality(String),
+  browser      LowCardinality(String),
+  os           LowCardinality(String),
+  country      LowCardinality(String),
+  views_state    AggregateFunction(count),
+  users_state    AggregateFunction(uniqExact, String),
+  sessions_state AggregateFunction(uniqExact, String)
+)
+ENGINE = AggregatingMergeTree
+PARTITION BY toYYYYMM(event_date)
+ORDER BY (site_id, event_date, event_name, device_type, browser, os, country)`
+	if _, err := c.db.ExecContext(ctx, rollupSiteDDL); err != nil {
+		return err
+	}
+
+	const rollupSiteMVDDL = `
+CREATE MATERIALIZED VIEW IF NOT EXISTS events_daily_site_mv
+TO events_daily_site
+AS SELECT
+  site_id,
+  event_date,
+  event_name,
+  device_type,
+  browser,
+  os,
+  country,
+  countState() AS views_state,
+  uniqExactState(user_id) AS users_state,
+  uniqExactState(session_id) AS sessions_state
+FROM events
+GROUP BY site_id, event_date, event_name, device_type, browser, os, country`
+	if _, err := c.db.ExecContext(ctx, rollupSiteMVDDL); err != nil {
+		return err
+	}
+
+	const rollupURLDDL = `
+CREATE TABLE IF NOT EXISTS events_daily_url
+(
+  site_id     LowCardinality(String),
+  event_date  Date,
+  url         String,
+  views_state AggregateFunction(count)
+)
+ENGINE = AggregatingMergeTree
+PARTITION BY toYYYYMM(event_date)
+ORDER BY (site_id, event_date, url)`
+	if _, err := c.db.ExecContext(ctx, rollupURLDDL); err != nil {
+		return err
+	}
+
+	const rollupURLMVDDL = `
+CREATE MATERIALIZED VIEW IF NOT EXISTS events_daily_url_mv
+TO events_daily_url
+AS SELECT
+  site_id,
+  event_date,
+  url,
+  countState() AS views_state
+FROM events
+GROUP BY site_id, event_date, url`
+	_, err := c.db.ExecContext(ctx, rollupURLMVDDL)
 	return err
 }
 
@@ -84,9 +167,11 @@ func (c *Client) InsertBatch(ctx context.Context, events []model.EnrichedEvent)
 INSERT INTO events (
 	event_time, event_date, event_name, user_id, session_id, site_id,
 	url, referrer, utm_source, utm_medium, utm_campaign,
-	country, device_type, browser, os, ip_hash, payload, _ingested_at
+	country, region, city, asn,
+	device_type, device_brand, browser, browser_version, os, os_version,
+	ip_hash, payload, _ingested_at
 ) VALUES (
-	?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+	?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
 )`)
 	if err != nil {
 		_ = tx.Rollback()
@@ -114,9 +199,15 @@ INSERT INTO events (
 			evt.UTMMedium,
 			evt.UTMCampaign,
 			evt.Country,
+			evt.Region,
+			evt.City,
+			evt.ASN,
 			evt.DeviceType,
+			evt.DeviceBrand,
 			evt.Browser,
+			evt.BrowserVersion,
 			evt.OS,
+			evt.OSVersion,
 			evt.IPHash,
 			string(payload),
 			evt.IngestedAt,
@@ -140,38 +231,128 @@ type TopPage struct {
 	Views int64  `json:"views"`
 }
 
-// Pageviews returns daily counts for a site.
-func (c *Client) Pageviews(ctx context.Context, siteID string, from, to time.Time) ([]MetricPoint, error) {
-	rows, err := c.db.QueryContext(ctx, `
+// topPagesOverfetch controls how many extra rows TopPages pulls from each
+// of the rollup/raw segments before re-ranking the merged set, so a page
+// that's popular across the whole range but not in either segment's own
+// top-N isn't dropped.
+const topPagesOverfetch = 3
+
+// rollupWindow splits [from, to] into a historical segment that the daily
+// rollup tables (events_daily_site/events_daily_url) can serve, and a
+// same-day segment that must come from the raw events table, since the
+// rollups' materialized views only guarantee a day's data is complete
+// once that day is over. Both returned ranges are inclusive; useRollup /
+// useRaw report whether that segment is non-empty.
+func rollupWindow(from, to time.Time) (rollupFrom, rollupTo time.Time, useRollup bool, rawFrom, rawTo time.Time, useRaw bool) {
+	today := dateOnly(time.Now().UTC())
+	from = dateOnly(from)
+	to = dateOnly(to)
+
+	rollupTo = today.AddDate(0, 0, -1)
+	if to.Before(rollupTo) {
+		rollupTo = to
+	}
+	if !rollupTo.Before(from) {
+		useRollup = true
+		rollupFrom = from
+	}
+
+	rawFrom = from
+	if today.After(rawFrom) {
+		rawFrom = today
+	}
+	rawTo = to
+	if !rawTo.Before(rawFrom) {
+		useRaw = true
+	}
+	return
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Pageviews returns daily counts for a site, reading from the
+// events_daily_site rollup for historical days and the raw events table
+// for today. Pass raw=true to always scan the raw table (debugging).
+func (c *Client) Pageviews(ctx context.Context, siteID string, from, to time.Time, raw bool) ([]MetricPoint, error) {
+	if raw {
+		return c.pageviewsRaw(ctx, siteID, from, to)
+	}
+	rollupFrom, rollupTo, useRollup, rawFrom, rawTo, useRaw := rollupWindow(from, to)
+	var series []MetricPoint
+	if useRollup {
+		rows, err := c.queryDailyMetric(ctx, `
+SELECT event_date, countMerge(views_state) AS views
+FROM events_daily_site
+WHERE site_id = ? AND event_date BETWEEN ? AND ?
+GROUP BY event_date
+ORDER BY event_date ASC`, siteID, rollupFrom, rollupTo)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, rows...)
+	}
+	if useRaw {
+		rows, err := c.pageviewsRaw(ctx, siteID, rawFrom, rawTo)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, rows...)
+	}
+	return series, nil
+}
+
+func (c *Client) pageviewsRaw(ctx context.Context, siteID string, from, to time.Time) ([]MetricPoint, error) {
+	return c.queryDailyMetric(ctx, `
 SELECT event_date, count() AS views
 FROM events
 WHERE site_id = ? AND event_date BETWEEN ? AND ?
 GROUP BY event_date
 ORDER BY event_date ASC`, siteID, from, to)
-	if err != nil {
-		return nil, err
+}
+
+// UniqueUsers returns daily unique counts, following the same rollup/raw
+// split as Pageviews.
+func (c *Client) UniqueUsers(ctx context.Context, siteID string, from, to time.Time, raw bool) ([]MetricPoint, error) {
+	if raw {
+		return c.uniqueUsersRaw(ctx, siteID, from, to)
 	}
-	defer rows.Close()
+	rollupFrom, rollupTo, useRollup, rawFrom, rawTo, useRaw := rollupWindow(from, to)
 	var series []MetricPoint
-	for rows.Next() {
-		var date time.Time
-		var value int64
-		if err := rows.Scan(&date, &value); err != nil {
+	if useRollup {
+		rows, err := c.queryDailyMetric(ctx, `
+SELECT event_date, uniqExactMerge(users_state) AS uniques
+FROM events_daily_site
+WHERE site_id = ? AND event_date BETWEEN ? AND ?
+GROUP BY event_date
+ORDER BY event_date ASC`, siteID, rollupFrom, rollupTo)
+		if err != nil {
 			return nil, err
 		}
-		series = append(series, MetricPoint{Date: date, Value: value})
+		series = append(series, rows...)
 	}
-	return series, rows.Err()
+	if useRaw {
+		rows, err := c.uniqueUsersRaw(ctx, siteID, rawFrom, rawTo)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, rows...)
+	}
+	return series, nil
 }
 
-// UniqueUsers returns daily unique counts.
-func (c *Client) UniqueUsers(ctx context.Context, siteID string, from, to time.Time) ([]MetricPoint, error) {
-	rows, err := c.db.QueryContext(ctx, `
+func (c *Client) uniqueUsersRaw(ctx context.Context, siteID string, from, to time.Time) ([]MetricPoint, error) {
+	return c.queryDailyMetric(ctx, `
 SELECT event_date, uniqExact(user_id) AS uniques
 FROM events
 WHERE site_id = ? AND event_date BETWEEN ? AND ?
 GROUP BY event_date
 ORDER BY event_date ASC`, siteID, from, to)
+}
+
+func (c *Client) queryDailyMetric(ctx context.Context, query, siteID string, from, to time.Time) ([]MetricPoint, error) {
+	rows, err := c.db.QueryContext(ctx, query, siteID, from, to)
 	if err != nil {
 		return nil, err
 	}
@@ -188,15 +369,69 @@ ORDER BY event_date ASC`, siteID, from, to)
 	return series, rows.Err()
 }
 
-// TopPages returns the top URLs for the timeframe.
-func (c *Client) TopPages(ctx context.Context, siteID string, from, to time.Time, limit int) ([]TopPage, error) {
-	rows, err := c.db.QueryContext(ctx, `
+// TopPages returns the top URLs for the timeframe, merging the
+// events_daily_url rollup (historical days) with the raw events table
+// (today) when raw is false.
+func (c *Client) TopPages(ctx context.Context, siteID string, from, to time.Time, limit int, raw bool) ([]TopPage, error) {
+	if raw {
+		return c.topPagesRaw(ctx, siteID, from, to, limit)
+	}
+	rollupFrom, rollupTo, useRollup, rawFrom, rawTo, useRaw := rollupWindow(from, to)
+	counts := make(map[string]int64)
+	if useRollup {
+		rows, err := c.queryTopPages(ctx, `
+SELECT url, countMerge(views_state) AS views
+FROM events_daily_url
+WHERE site_id = ? AND event_date BETWEEN ? AND ?
+GROUP BY url
+ORDER BY views DESC
+LIMIT ?`, siteID, rollupFrom, rollupTo, limit*topPagesOverfetch)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range rows {
+			counts[p.URL] += p.Views
+		}
+	}
+	if useRaw {
+		rows, err := c.queryTopPages(ctx, `
+SELECT url, count() AS views
+FROM events
+WHERE site_id = ? AND event_date BETWEEN ? AND ?
+GROUP BY url
+ORDER BY views DESC
+LIMIT ?`, siteID, rawFrom, rawTo, limit*topPagesOverfetch)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range rows {
+			counts[p.URL] += p.Views
+		}
+	}
+
+	out := make([]TopPage, 0, len(counts))
+	for url, views := range counts {
+		out = append(out, TopPage{URL: url, Views: views})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Views > out[j].Views })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (c *Client) topPagesRaw(ctx context.Context, siteID string, from, to time.Time, limit int) ([]TopPage, error) {
+	return c.queryTopPages(ctx, `
 SELECT url, count() AS views
 FROM events
 WHERE site_id = ? AND event_date BETWEEN ? AND ?
 GROUP BY url
 ORDER BY views DESC
 LIMIT ?`, siteID, from, to, limit)
+}
+
+func (c *Client) queryTopPages(ctx context.Context, query, siteID string, from, to time.Time, limit int) ([]TopPage, error) {
+	rows, err := c.db.QueryContext(ctx, query, siteID, from, to, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -212,6 +447,99 @@ LIMIT ?`, siteID, from, to, limit)
 	return out, rows.Err()
 }
 
+// GeoBreakdown holds aggregated visit counts for a country/region pair.
+type GeoBreakdown struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+	Views   int64  `json:"views"`
+}
+
+// TopCountries returns the top country/region breakdowns for the timeframe.
+func (c *Client) TopCountries(ctx context.Context, siteID string, from, to time.Time, limit int) ([]GeoBreakdown, error) {
+	rows, err := c.db.QueryContext(ctx, `
+SELECT country, region, count() AS views
+FROM events
+WHERE site_id = ? AND event_date BETWEEN ? AND ?
+GROUP BY country, region
+ORDER BY views DESC
+LIMIT ?`, siteID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []GeoBreakdown
+	for rows.Next() {
+		var record GeoBreakdown
+		if err := rows.Scan(&record.Country, &record.Region, &record.Views); err != nil {
+			return nil, err
+		}
+		out = append(out, record)
+	}
+	return out, rows.Err()
+}
+
+// BrowserBreakdown holds aggregated visit counts for a browser/version pair.
+type BrowserBreakdown struct {
+	Browser string `json:"browser"`
+	Version string `json:"version"`
+	Views   int64  `json:"views"`
+}
+
+// TopBrowsers returns the top browser/version breakdowns for the timeframe.
+func (c *Client) TopBrowsers(ctx context.Context, siteID string, from, to time.Time, limit int) ([]BrowserBreakdown, error) {
+	rows, err := c.db.QueryContext(ctx, `
+SELECT browser, browser_version, count() AS views
+FROM events
+WHERE site_id = ? AND event_date BETWEEN ? AND ?
+GROUP BY browser, browser_version
+ORDER BY views DESC
+LIMIT ?`, siteID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []BrowserBreakdown
+	for rows.Next() {
+		var record BrowserBreakdown
+		if err := rows.Scan(&record.Browser, &record.Version, &record.Views); err != nil {
+			return nil, err
+		}
+		out = append(out, record)
+	}
+	return out, rows.Err()
+}
+
+// OSBreakdown holds aggregated visit counts for an OS/version pair.
+type OSBreakdown struct {
+	OS      string `json:"os"`
+	Version string `json:"version"`
+	Views   int64  `json:"views"`
+}
+
+// TopOS returns the top OS/version breakdowns for the timeframe.
+func (c *Client) TopOS(ctx context.Context, siteID string, from, to time.Time, limit int) ([]OSBreakdown, error) {
+	rows, err := c.db.QueryContext(ctx, `
+SELECT os, os_version, count() AS views
+FROM events
+WHERE site_id = ? AND event_date BETWEEN ? AND ?
+GROUP BY os, os_version
+ORDER BY views DESC
+LIMIT ?`, siteID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []OSBreakdown
+	for rows.Next() {
+		var record OSBreakdown
+		if err := rows.Scan(&record.OS, &record.Version, &record.Views); err != nil {
+			return nil, err
+		}
+		out = append(out, record)
+	}
+	return out, rows.Err()
+}
+
 // CountEvents returns the total rows, useful for tests.
 func (c *Client) CountEvents(ctx context.Context) (int64, error) {
 	row := c.db.QueryRowContext(ctx, `SELECT count() FROM events`)
@@ -0,0 +1,43 @@
+package ch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollupWindowSplitsOnToday(t *testing.T) {
+	today := dateOnly(time.Now().UTC())
+	yesterday := today.AddDate(0, 0, -1)
+	weekAgo := today.AddDate(0, 0, -7)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	t.Run("entirely historical", func(t *testing.T) {
+		rollupFrom, rollupTo, useRollup, _, _, useRaw := rollupWindow(weekAgo, yesterday)
+		if !useRollup || useRaw {
+			t.Fatalf("expected rollup-only, got useRollup=%v useRaw=%v", useRollup, useRaw)
+		}
+		if !rollupFrom.Equal(weekAgo) || !rollupTo.Equal(yesterday) {
+			t.Fatalf("unexpected rollup range: %v..%v", rollupFrom, rollupTo)
+		}
+	})
+
+	t.Run("entirely today or later", func(t *testing.T) {
+		rollupFrom, rollupTo, useRollup, rawFrom, rawTo, useRaw := rollupWindow(today, tomorrow)
+		if useRollup {
+			t.Fatalf("expected raw-only, got rollup range %v..%v", rollupFrom, rollupTo)
+		}
+		if !useRaw || !rawFrom.Equal(today) || !rawTo.Equal(tomorrow) {
+			t.Fatalf("unexpected raw range: %v..%v (useRaw=%v)", rawFrom, rawTo, useRaw)
+		}
+	})
+
+	t.Run("spans today", func(t *testing.T) {
+		rollupFrom, rollupTo, useRollup, rawFrom, rawTo, useRaw := rollupWindow(weekAgo, tomorrow)
+		if !useRollup || !rollupFrom.Equal(weekAgo) || !rollupTo.Equal(yesterday) {
+			t.Fatalf("unexpected rollup range: %v..%v (useRollup=%v)", rollupFrom, rollupTo, useRollup)
+		}
+		if !useRaw || !rawFrom.Equal(today) || !rawTo.Equal(tomorrow) {
+			t.Fatalf("unexpected raw range: %v..%v (useRaw=%v)", rawFrom, rawTo, useRaw)
+		}
+	})
+}
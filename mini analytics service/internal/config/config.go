@@ -8,38 +8,130 @@ import (
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"tiny-analytics/internal/kafka"
 )
 
 // Config holds shared service configuration sourced from environment variables.
 type Config struct {
-	IngestAddr          string
-	QueryAddr           string
-	EnricherMetricsAddr string
-	LoaderMetricsAddr   string
-	KafkaBrokers        []string
-	KafkaTopicRaw       string
-	KafkaTopicEnriched  string
-	ClickHouseDSN       string
-	HMACSecret          string
-	IPHashSalt          string
-	CORSAllowOrigins    []string
-	BotUserAgents       []string
-	BatchSize           int
-	BatchInterval       time.Duration
-	Sites               map[string]SiteCredential
-	SitesConfigPath     string
+	IngestAddr            string
+	QueryAddr             string
+	EnricherMetricsAddr   string
+	LoaderMetricsAddr     string
+	WebhookAddr           string
+	KafkaBrokers          []string
+	KafkaTopicRaw         string
+	KafkaTopicEnriched    string
+	KafkaTopicWebhookDLQ  string
+	KafkaSecurity         KafkaSecurity
+	ClickHouseDSN         string
+	HMACSecret            string
+	IPHashSalt            string
+	CORSAllowOrigins      []string
+	BotUserAgents         []string
+	BatchSize             int
+	BatchInterval         time.Duration
+	EnricherBatchSize     int
+	EnricherBatchInterval time.Duration
+	SignatureSkew         time.Duration
+	MaxBodyBytes          int64
+	GeoIPDBPath           string
+	GeoIPASNDBPath        string
+	Sites                 map[string]SiteCredential
+	SitesConfigPath       string
+	// NonceRedisAddr, when set, backs the ingest nonce-replay cache with
+	// Redis instead of an in-process map, so replay protection is shared
+	// across ingest-api replicas. Empty leaves it in-process.
+	NonceRedisAddr string
+	// LoaderWALDir, when set, backs the loader's batcher with an on-disk
+	// WAL so a crash between consuming from Kafka and inserting into
+	// ClickHouse doesn't lose the in-flight batch. Empty disables the WAL.
+	LoaderWALDir string
+	// LoaderWALMaxBytes forces an early flush once the WAL segment grows
+	// past it.
+	LoaderWALMaxBytes int64
+	// LoaderDeadLetterDir stores batches that exhausted insertWithRetry's
+	// attempts, for operators to inspect or replay with cmd/wal-tool.
+	LoaderDeadLetterDir string
 }
 
 // SiteCredential defines API key / HMAC secrets for a tenant site.
 type SiteCredential struct {
 	APIKey     string `yaml:"api_key"`
 	HMACSecret string `yaml:"hmac_secret"`
+	// RequireSignedTimestamp gates a site onto the replay-resistant signing
+	// scheme (X-TA-Timestamp/X-TA-Nonce). It defaults to false so existing
+	// v1 clients keep working until they're migrated.
+	RequireSignedTimestamp bool `yaml:"require_signed_timestamp"`
+	// Webhooks lists the outbound subscribers that should receive this
+	// site's enriched events.
+	Webhooks []Webhook `yaml:"webhooks"`
+	// GA4MeasurementID, when set, lets this site accept events via the GA4
+	// Measurement Protocol endpoint (POST /mp/collect?measurement_id=...),
+	// authenticated by comparing the request's api_secret against APIKey.
+	GA4MeasurementID string `yaml:"ga4_measurement_id"`
+}
+
+// SiteByGA4MeasurementID returns the siteID whose GA4MeasurementID matches
+// measurementID, and whether one was found.
+func (c Config) SiteByGA4MeasurementID(measurementID string) (string, SiteCredential, bool) {
+	for siteID, cred := range c.Sites {
+		if cred.GA4MeasurementID != "" && cred.GA4MeasurementID == measurementID {
+			return siteID, cred, true
+		}
+	}
+	return "", SiteCredential{}, false
+}
+
+// Webhook describes one outbound subscriber for a site's enriched events.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Secret signs the delivered body so subscribers can verify it came
+	// from us, using the same auth.ComputeSignature scheme as ingest.
+	Secret string `yaml:"secret"`
+	// EventNames restricts delivery to matching model.Event.EventName
+	// values. An empty list matches every event.
+	EventNames []string `yaml:"event_names"`
+	MaxRetries int      `yaml:"max_retries"`
 }
 
 type sitesFile struct {
 	Sites map[string]SiteCredential `yaml:"sites"`
 }
 
+// KafkaSecurity configures how the Kafka clients authenticate with the
+// broker, so the same process can talk to a local plaintext cluster in dev
+// and a managed SASL_SSL cluster (Confluent Cloud, MSK, Redpanda Cloud) in
+// production.
+type KafkaSecurity struct {
+	// Protocol is one of "plaintext", "tls", or "sasl_ssl".
+	Protocol string
+	// SASLMechanism is one of "plain", "scram-sha-256", or "scram-sha-512".
+	// Only read when Protocol is "sasl_ssl".
+	SASLMechanism      string
+	Username           string
+	Password           string
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// ToKafkaOptions converts the parsed security settings into the Options
+// type consumed by internal/kafka's reader/writer constructors.
+func (s KafkaSecurity) ToKafkaOptions() kafka.Options {
+	return kafka.Options{
+		Protocol:           s.Protocol,
+		SASLMechanism:      s.SASLMechanism,
+		Username:           s.Username,
+		Password:           s.Password,
+		CAFile:             s.CAFile,
+		CertFile:           s.CertFile,
+		KeyFile:            s.KeyFile,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	}
+}
+
 // Load parses process environment variables into a Config struct, applying defaults when unset.
 func Load() (Config, error) {
 	path := getenv("SITES_CONFIG_PATH", "config/sites.dev.yml")
@@ -49,22 +141,44 @@ func Load() (Config, error) {
 	}
 
 	cfg := Config{
-		IngestAddr:          getenv("INGEST_ADDR", ":8080"),
-		QueryAddr:           getenv("QUERY_ADDR", ":8081"),
-		EnricherMetricsAddr: getenv("ENRICHER_METRICS_ADDR", ":9100"),
-		LoaderMetricsAddr:   getenv("LOADER_METRICS_ADDR", ":9101"),
-		KafkaBrokers:        splitAndTrim(getenv("KAFKA_BROKERS", "localhost:9092")),
-		KafkaTopicRaw:       getenv("KAFKA_TOPIC_RAW", "events.raw"),
-		KafkaTopicEnriched:  getenv("KAFKA_TOPIC_ENRICHED", "events.enriched"),
-		ClickHouseDSN:       getenv("CLICKHOUSE_DSN", "clickhouse://default:@localhost:9000?database=default&dial_timeout=5s&compress=true&allow_experimental_object_type=1"),
-		HMACSecret:          os.Getenv("HMAC_SECRET"),
-		IPHashSalt:          getenv("IP_HASH_SALT", "dev-salt"),
-		CORSAllowOrigins:    splitAndTrimAllowEmpty(getenv("CORS_ALLOW_ORIGINS", "*")),
-		BotUserAgents:       splitAndTrimAllowEmpty(getenv("BOT_UA_DENYLIST", "bot,crawler,spider")),
-		BatchSize:           atoiDefault("LOADER_BATCH_SIZE", 1000),
-		BatchInterval:       durationDefault("LOADER_BATCH_INTERVAL_MS", 800),
-		Sites:               sites,
-		SitesConfigPath:     path,
+		IngestAddr:           getenv("INGEST_ADDR", ":8080"),
+		QueryAddr:            getenv("QUERY_ADDR", ":8081"),
+		EnricherMetricsAddr:  getenv("ENRICHER_METRICS_ADDR", ":9100"),
+		LoaderMetricsAddr:    getenv("LOADER_METRICS_ADDR", ":9101"),
+		WebhookAddr:          getenv("WEBHOOK_DISPATCHER_ADDR", ":9102"),
+		KafkaBrokers:         splitAndTrim(getenv("KAFKA_BROKERS", "localhost:9092")),
+		KafkaTopicRaw:        getenv("KAFKA_TOPIC_RAW", "events.raw"),
+		KafkaTopicEnriched:   getenv("KAFKA_TOPIC_ENRICHED", "events.enriched"),
+		KafkaTopicWebhookDLQ: getenv("KAFKA_TOPIC_WEBHOOK_DLQ", "webhooks.dlq"),
+		KafkaSecurity: KafkaSecurity{
+			Protocol:           getenv("KAFKA_SECURITY_PROTOCOL", "plaintext"),
+			SASLMechanism:      getenv("KAFKA_SASL_MECHANISM", "plain"),
+			Username:           os.Getenv("KAFKA_SASL_USERNAME"),
+			Password:           os.Getenv("KAFKA_SASL_PASSWORD"),
+			CAFile:             os.Getenv("KAFKA_TLS_CA_FILE"),
+			CertFile:           os.Getenv("KAFKA_TLS_CERT_FILE"),
+			KeyFile:            os.Getenv("KAFKA_TLS_KEY_FILE"),
+			InsecureSkipVerify: os.Getenv("KAFKA_TLS_INSECURE_SKIP_VERIFY") == "true",
+		},
+		ClickHouseDSN:         getenv("CLICKHOUSE_DSN", "clickhouse://default:@localhost:9000?database=default&dial_timeout=5s&compress=true&allow_experimental_object_type=1"),
+		HMACSecret:            os.Getenv("HMAC_SECRET"),
+		IPHashSalt:            getenv("IP_HASH_SALT", "dev-salt"),
+		CORSAllowOrigins:      splitAndTrimAllowEmpty(getenv("CORS_ALLOW_ORIGINS", "*")),
+		BotUserAgents:         splitAndTrimAllowEmpty(getenv("BOT_UA_DENYLIST", "bot,crawler,spider")),
+		BatchSize:             atoiDefault("LOADER_BATCH_SIZE", 1000),
+		BatchInterval:         durationDefault("LOADER_BATCH_INTERVAL_MS", 800),
+		EnricherBatchSize:     atoiDefault("ENRICHER_BATCH_SIZE", 500),
+		EnricherBatchInterval: durationDefault("ENRICHER_BATCH_INTERVAL_MS", 500),
+		SignatureSkew:         durationDefault("SIGNATURE_SKEW_MS", 5*60*1000),
+		MaxBodyBytes:          int64Default("MAX_BODY_BYTES", 512*1024),
+		GeoIPDBPath:           os.Getenv("GEOIP_DB_PATH"),
+		GeoIPASNDBPath:        os.Getenv("GEOIP_ASN_DB_PATH"),
+		Sites:                 sites,
+		SitesConfigPath:       path,
+		NonceRedisAddr:        os.Getenv("NONCE_REDIS_ADDR"),
+		LoaderWALDir:          os.Getenv("LOADER_WAL_DIR"),
+		LoaderWALMaxBytes:     int64Default("LOADER_WAL_MAX_BYTES", 64*1024*1024),
+		LoaderDeadLetterDir:   getenv("LOADER_DEAD_LETTER_DIR", "data/loader-deadletter"),
 	}
 	return cfg, nil
 }
@@ -105,6 +219,15 @@ func atoiDefault(key string, def int) int {
 	return def
 }
 
+func int64Default(key string, def int64) int64 {
+	if val, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
 func durationDefault(key string, defMS int) time.Duration {
 	if val, ok := os.LookupEnv(key); ok {
 		if parsed, err := strconv.Atoi(val); err == nil {
@@ -25,24 +25,30 @@ type RawEvent struct {
 
 // EnrichedEvent is the denormalized document ready for ClickHouse ingestion.
 type EnrichedEvent struct {
-	EventTime   time.Time      `json:"event_time"`
-	EventDate   time.Time      `json:"event_date"`
-	EventName   string         `json:"event_name"`
-	UserID      string         `json:"user_id"`
-	SessionID   string         `json:"session_id"`
-	SiteID      string         `json:"site_id"`
-	URL         string         `json:"url"`
-	Referrer    string         `json:"referrer"`
-	UTMSource   string         `json:"utm_source"`
-	UTMMedium   string         `json:"utm_medium"`
-	UTMCampaign string         `json:"utm_campaign"`
-	Country     string         `json:"country"`
-	DeviceType  string         `json:"device_type"`
-	Browser     string         `json:"browser"`
-	OS          string         `json:"os"`
-	IPHash      string         `json:"ip_hash"`
-	Payload     map[string]any `json:"payload"`
-	IngestedAt  time.Time      `json:"_ingested_at"`
+	EventTime      time.Time      `json:"event_time"`
+	EventDate      time.Time      `json:"event_date"`
+	EventName      string         `json:"event_name"`
+	UserID         string         `json:"user_id"`
+	SessionID      string         `json:"session_id"`
+	SiteID         string         `json:"site_id"`
+	URL            string         `json:"url"`
+	Referrer       string         `json:"referrer"`
+	UTMSource      string         `json:"utm_source"`
+	UTMMedium      string         `json:"utm_medium"`
+	UTMCampaign    string         `json:"utm_campaign"`
+	Country        string         `json:"country"`
+	Region         string         `json:"region"`
+	City           string         `json:"city"`
+	ASN            string         `json:"asn"`
+	DeviceType     string         `json:"device_type"`
+	DeviceBrand    string         `json:"device_brand"`
+	Browser        string         `json:"browser"`
+	BrowserVersion string         `json:"browser_version"`
+	OS             string         `json:"os"`
+	OSVersion      string         `json:"os_version"`
+	IPHash         string         `json:"ip_hash"`
+	Payload        map[string]any `json:"payload"`
+	IngestedAt     time.Time      `json:"_ingested_at"`
 }
 
 // NewRawEvent builds a RawEvent from a validated Event and server metadata.
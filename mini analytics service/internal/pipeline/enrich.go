@@ -4,14 +4,17 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"net/url"
+	"strings"
 	"time"
 
 	"tiny-analytics/internal/model"
 	"tiny-analytics/internal/util"
 )
 
-// Enrich transforms a raw event into the ClickHouse-ready schema.
-func Enrich(raw model.RawEvent, ipSalt string) (model.EnrichedEvent, error) {
+// Enrich transforms a raw event into the ClickHouse-ready schema. geo
+// resolves raw.IP to location data; pass NoopGeoResolver{} when no GeoIP
+// database is configured.
+func Enrich(raw model.RawEvent, ipSalt string, geo GeoResolver) (model.EnrichedEvent, error) {
 	eventTime := time.UnixMilli(raw.TS).UTC()
 	if raw.TS == 0 {
 		eventTime = time.Now().UTC()
@@ -19,9 +22,7 @@ func Enrich(raw model.RawEvent, ipSalt string) (model.EnrichedEvent, error) {
 	eventDate := time.Date(eventTime.Year(), eventTime.Month(), eventTime.Day(), 0, 0, 0, 0, time.UTC)
 	utmSource, utmMedium, utmCampaign := parseUTM(raw.URL)
 
-	deviceType := util.ParseDeviceType(raw.UA)
-	browser := util.ParseBrowser(raw.UA)
-	os := util.ParseOS(raw.UA)
+	uaInfo := util.ParseUA(raw.UA)
 
 	payload := raw.Props
 	if payload == nil {
@@ -29,29 +30,53 @@ func Enrich(raw model.RawEvent, ipSalt string) (model.EnrichedEvent, error) {
 	}
 
 	ipHash := hashIP(ipSalt, raw.IP)
+	geoInfo := geo.Lookup(raw.IP)
 
 	return model.EnrichedEvent{
-		EventTime:   eventTime,
-		EventDate:   eventDate,
-		EventName:   raw.EventName,
-		UserID:      raw.UserID,
-		SessionID:   raw.SessionID,
-		SiteID:      raw.SiteID,
-		URL:         raw.URL,
-		Referrer:    raw.Referrer,
-		UTMSource:   utmSource,
-		UTMMedium:   utmMedium,
-		UTMCampaign: utmCampaign,
-		Country:     "unknown",
-		DeviceType:  deviceType,
-		Browser:     browser,
-		OS:          os,
-		IPHash:      ipHash,
-		Payload:     payload,
-		IngestedAt:  time.Now().UTC(),
+		EventTime:      eventTime,
+		EventDate:      eventDate,
+		EventName:      raw.EventName,
+		UserID:         raw.UserID,
+		SessionID:      raw.SessionID,
+		SiteID:         raw.SiteID,
+		URL:            raw.URL,
+		Referrer:       raw.Referrer,
+		UTMSource:      utmSource,
+		UTMMedium:      utmMedium,
+		UTMCampaign:    utmCampaign,
+		Country:        geoInfo.Country,
+		Region:         geoInfo.Region,
+		City:           geoInfo.City,
+		ASN:            geoInfo.ASN,
+		DeviceType:     deviceTypeBucket(uaInfo.DeviceFamily),
+		DeviceBrand:    uaInfo.DeviceBrand,
+		Browser:        strings.ToLower(uaInfo.Family),
+		BrowserVersion: uaInfo.Version,
+		OS:             strings.ToLower(uaInfo.OSFamily),
+		OSVersion:      uaInfo.OSVersion,
+		IPHash:         ipHash,
+		Payload:        payload,
+		IngestedAt:     time.Now().UTC(),
 	}, nil
 }
 
+// deviceTypeBucket collapses uap-core's fine-grained device families into
+// the coarse mobile/tablet/desktop/bot buckets the query API groups by.
+func deviceTypeBucket(deviceFamily string) string {
+	switch deviceFamily {
+	case "iPhone", "Generic Smartphone":
+		return "mobile"
+	case "iPad", "Generic Tablet":
+		return "tablet"
+	case "Spider":
+		return "bot"
+	case "Other", "":
+		return "desktop"
+	default:
+		return "desktop"
+	}
+}
+
 func parseUTM(rawURL string) (source, medium, campaign string) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
@@ -0,0 +1,25 @@
+package pipeline
+
+// GeoInfo is the location data resolved for an IP address.
+type GeoInfo struct {
+	Country string
+	Region  string
+	City    string
+	ASN     string
+}
+
+// GeoResolver looks up location data for an IP address. Implementations
+// must be safe for concurrent use.
+type GeoResolver interface {
+	Lookup(ip string) GeoInfo
+}
+
+// NoopGeoResolver reports everything as unknown. It's the zero-config
+// default so Enrich works the same as before GeoIP support existed when no
+// database path is configured.
+type NoopGeoResolver struct{}
+
+// Lookup always returns the "unknown" placeholders.
+func (NoopGeoResolver) Lookup(string) GeoInfo {
+	return GeoInfo{Country: "unknown"}
+}
@@ -0,0 +1,185 @@
+package pipeline
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// cityRecord maps the subset of GeoLite2-City fields Enrich needs.
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// asnRecord maps the subset of GeoLite2-ASN fields Enrich needs.
+type asnRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// MaxMindGeoResolver resolves IPs against local GeoLite2-City and,
+// optionally, GeoLite2-ASN mmdb files. It watches both files for changes
+// and hot-reloads them so long-running processes (the loader, the
+// enricher) pick up updated databases without a restart.
+type MaxMindGeoResolver struct {
+	cityPath string
+	asnPath  string
+
+	city atomic.Pointer[maxminddb.Reader]
+	asn  atomic.Pointer[maxminddb.Reader]
+
+	stop chan struct{}
+}
+
+// readerCloseGrace is how long a reader swapped out by a hot reload is
+// kept open before Close is called on it. maxminddb.Reader.Close munmaps
+// the backing buffer unconditionally, with no reference counting against
+// in-flight Lookups, so closing it the instant it's swapped out risks a
+// use-after-free in any Lookup goroutine still running against it; the
+// grace period gives those calls time to finish first.
+const readerCloseGrace = 5 * time.Second
+
+// NewMaxMindGeoResolver opens cityPath (required) and asnPath (optional,
+// pass "" to skip ASN enrichment), then starts a background watcher that
+// reloads either file when its modification time changes.
+func NewMaxMindGeoResolver(cityPath, asnPath string, pollInterval time.Duration) (*MaxMindGeoResolver, error) {
+	r := &MaxMindGeoResolver{cityPath: cityPath, asnPath: asnPath, stop: make(chan struct{})}
+
+	city, err := maxminddb.Open(cityPath)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip city db %s: %w", cityPath, err)
+	}
+	r.city.Store(city)
+
+	if asnPath != "" {
+		asn, err := maxminddb.Open(asnPath)
+		if err != nil {
+			city.Close()
+			return nil, fmt.Errorf("open geoip asn db %s: %w", asnPath, err)
+		}
+		r.asn.Store(asn)
+	}
+
+	if pollInterval <= 0 {
+		pollInterval = 30 * time.Second
+	}
+	go r.watch(pollInterval)
+	return r, nil
+}
+
+// Lookup resolves ip against the loaded databases. It returns
+// GeoInfo{Country: "unknown"} for unparsable or unresolved addresses
+// rather than an error, matching the zero-value behavior Enrich had
+// before GeoIP support existed.
+func (r *MaxMindGeoResolver) Lookup(ip string) GeoInfo {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return GeoInfo{Country: "unknown"}
+	}
+
+	info := GeoInfo{Country: "unknown"}
+	if city := r.city.Load(); city != nil {
+		var rec cityRecord
+		if err := city.Lookup(addr, &rec); err == nil {
+			if rec.Country.ISOCode != "" {
+				info.Country = rec.Country.ISOCode
+			}
+			if len(rec.Subdivisions) > 0 {
+				info.Region = rec.Subdivisions[0].ISOCode
+			}
+			info.City = rec.City.Names["en"]
+		}
+	}
+	if asn := r.asn.Load(); asn != nil {
+		var rec asnRecord
+		if err := asn.Lookup(addr, &rec); err == nil && rec.AutonomousSystemNumber != 0 {
+			info.ASN = "AS" + strconv.FormatUint(uint64(rec.AutonomousSystemNumber), 10)
+		}
+	}
+	return info
+}
+
+// Close stops the watcher and releases both mmdb file handles.
+func (r *MaxMindGeoResolver) Close() error {
+	close(r.stop)
+	if city := r.city.Load(); city != nil {
+		_ = city.Close()
+	}
+	if asn := r.asn.Load(); asn != nil {
+		_ = asn.Close()
+	}
+	return nil
+}
+
+func (r *MaxMindGeoResolver) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	cityModTime := modTime(r.cityPath)
+	asnModTime := modTime(r.asnPath)
+
+	for {
+		select {
+		case <-ticker.C:
+			if t := modTime(r.cityPath); !t.IsZero() && !t.Equal(cityModTime) {
+				if reloaded, err := maxminddb.Open(r.cityPath); err == nil {
+					if old := r.city.Swap(reloaded); old != nil {
+						closeAfterGrace(old)
+					}
+					cityModTime = t
+					log.Printf("geoip: reloaded city db %s", r.cityPath)
+				} else {
+					log.Printf("geoip: failed to reload city db %s: %v", r.cityPath, err)
+				}
+			}
+			if r.asnPath != "" {
+				if t := modTime(r.asnPath); !t.IsZero() && !t.Equal(asnModTime) {
+					if reloaded, err := maxminddb.Open(r.asnPath); err == nil {
+						if old := r.asn.Swap(reloaded); old != nil {
+							closeAfterGrace(old)
+						}
+						asnModTime = t
+						log.Printf("geoip: reloaded asn db %s", r.asnPath)
+					} else {
+						log.Printf("geoip: failed to reload asn db %s: %v", r.asnPath, err)
+					}
+				}
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// closeAfterGrace closes old after readerCloseGrace has elapsed, giving any
+// Lookup already in flight against it time to finish first.
+func closeAfterGrace(old *maxminddb.Reader) {
+	time.AfterFunc(readerCloseGrace, func() {
+		_ = old.Close()
+	})
+}
+
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,22 @@
+package pipeline
+
+// StubGeoResolver is an in-memory GeoResolver for tests and local
+// development, returning a fixed lookup table with a fallback for misses.
+type StubGeoResolver struct {
+	byIP    map[string]GeoInfo
+	Default GeoInfo
+}
+
+// NewStubGeoResolver builds a StubGeoResolver from a fixed IP -> GeoInfo
+// table. Lookups that miss the table return def.
+func NewStubGeoResolver(byIP map[string]GeoInfo, def GeoInfo) *StubGeoResolver {
+	return &StubGeoResolver{byIP: byIP, Default: def}
+}
+
+// Lookup returns the configured GeoInfo for ip, or the default if unset.
+func (s *StubGeoResolver) Lookup(ip string) GeoInfo {
+	if info, ok := s.byIP[ip]; ok {
+		return info
+	}
+	return s.Default
+}
@@ -0,0 +1,241 @@
+package util
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed uap_regexes.yaml
+var uapRegexesYAML []byte
+
+// UAInfo is the structured result of parsing a User-Agent string against
+// the regex database embedded from uap_regexes.yaml — a curated subset of
+// uap-core's patterns, not the full upstream database; see that file's
+// header for scope.
+type UAInfo struct {
+	Family       string
+	Version      string
+	OSFamily     string
+	OSVersion    string
+	DeviceFamily string
+	DeviceBrand  string
+	DeviceModel  string
+	IsBot        bool
+}
+
+type uaAgentPattern struct {
+	Regex             string `yaml:"regex"`
+	FamilyReplacement string `yaml:"family_replacement"`
+	V1Replacement     string `yaml:"v1_replacement"`
+	V2Replacement     string `yaml:"v2_replacement"`
+	compiled          *regexp.Regexp
+}
+
+type osAgentPattern struct {
+	Regex           string `yaml:"regex"`
+	OSReplacement   string `yaml:"os_replacement"`
+	OSV1Replacement string `yaml:"os_v1_replacement"`
+	OSV2Replacement string `yaml:"os_v2_replacement"`
+	compiled        *regexp.Regexp
+}
+
+type deviceAgentPattern struct {
+	Regex             string `yaml:"regex"`
+	RegexFlag         string `yaml:"regex_flag"`
+	DeviceReplacement string `yaml:"device_replacement"`
+	BrandReplacement  string `yaml:"brand_replacement"`
+	ModelReplacement  string `yaml:"model_replacement"`
+	compiled          *regexp.Regexp
+}
+
+type regexDB struct {
+	UserAgentParsers []uaAgentPattern     `yaml:"user_agent_parsers"`
+	OSParsers        []osAgentPattern     `yaml:"os_parsers"`
+	DeviceParsers    []deviceAgentPattern `yaml:"device_parsers"`
+}
+
+// botFamilies lists user_agent_parsers families that uap-core itself
+// classifies as a crawler rather than a browser, independent of the
+// device_parsers "Spider" match below.
+var botFamilies = map[string]bool{
+	"Googlebot":           true,
+	"bingbot":             true,
+	"Slurp":               true,
+	"DuckDuckBot":         true,
+	"YandexBot":           true,
+	"Baiduspider":         true,
+	"facebookexternalhit": true,
+}
+
+// UAParser classifies User-Agent strings using a compiled uap-core-format
+// regex database (see uap_regexes.yaml). It holds no mutable state after
+// construction and is safe for concurrent use.
+type UAParser struct {
+	uaParsers     []uaAgentPattern
+	osParsers     []osAgentPattern
+	deviceParsers []deviceAgentPattern
+}
+
+// NewUAParser parses and compiles the embedded regex database. It's
+// expected to be called once at startup; the returned *UAParser is then
+// reused for the life of the process.
+func NewUAParser() (*UAParser, error) {
+	var db regexDB
+	if err := yaml.Unmarshal(uapRegexesYAML, &db); err != nil {
+		return nil, fmt.Errorf("parse uap regex database: %w", err)
+	}
+
+	p := &UAParser{
+		uaParsers:     db.UserAgentParsers,
+		osParsers:     db.OSParsers,
+		deviceParsers: db.DeviceParsers,
+	}
+	for i := range p.uaParsers {
+		re, err := regexp.Compile(p.uaParsers[i].Regex)
+		if err != nil {
+			return nil, fmt.Errorf("compile user_agent_parsers[%d] %q: %w", i, p.uaParsers[i].Regex, err)
+		}
+		p.uaParsers[i].compiled = re
+	}
+	for i := range p.osParsers {
+		re, err := regexp.Compile(p.osParsers[i].Regex)
+		if err != nil {
+			return nil, fmt.Errorf("compile os_parsers[%d] %q: %w", i, p.osParsers[i].Regex, err)
+		}
+		p.osParsers[i].compiled = re
+	}
+	for i := range p.deviceParsers {
+		pattern := p.deviceParsers[i].Regex
+		if p.deviceParsers[i].RegexFlag == "i" {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile device_parsers[%d] %q: %w", i, p.deviceParsers[i].Regex, err)
+		}
+		p.deviceParsers[i].compiled = re
+	}
+	return p, nil
+}
+
+// MustNewUAParser is like NewUAParser but panics on failure. It's meant
+// for package-level initialization where the embedded database is
+// trusted to be valid.
+func MustNewUAParser() *UAParser {
+	p, err := NewUAParser()
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// defaultUAParser is compiled once at package init and backs the
+// package-level ParseUA helper, mirroring how the rest of this package
+// exposes stateless parsing functions.
+var defaultUAParser = MustNewUAParser()
+
+// ParseUA classifies ua using the default, package-level UAParser.
+func ParseUA(ua string) UAInfo {
+	return defaultUAParser.Parse(ua)
+}
+
+// Parse classifies a User-Agent string into family/version, OS, device,
+// and bot metadata. Fields are left blank ("Other"/"" per uap-core
+// convention) when nothing matches.
+func (p *UAParser) Parse(ua string) UAInfo {
+	info := UAInfo{
+		Family:       "Other",
+		OSFamily:     "Other",
+		DeviceFamily: "Other",
+	}
+	if ua == "" {
+		return info
+	}
+
+	for _, pat := range p.uaParsers {
+		m := pat.compiled.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+		info.Family = firstNonEmpty(replaceTokens(pat.FamilyReplacement, m), group(m, 1), "Other")
+		v1 := firstNonEmpty(replaceTokens(pat.V1Replacement, m), group(m, 1))
+		v2 := firstNonEmpty(replaceTokens(pat.V2Replacement, m), group(m, 2))
+		info.Version = joinVersion(v1, v2)
+		if botFamilies[info.Family] {
+			info.IsBot = true
+		}
+		break
+	}
+
+	for _, pat := range p.osParsers {
+		m := pat.compiled.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+		info.OSFamily = firstNonEmpty(replaceTokens(pat.OSReplacement, m), "Other")
+		v1 := firstNonEmpty(replaceTokens(pat.OSV1Replacement, m), group(m, 1))
+		v2 := firstNonEmpty(replaceTokens(pat.OSV2Replacement, m), group(m, 2))
+		info.OSVersion = joinVersion(v1, v2)
+		break
+	}
+
+	for _, pat := range p.deviceParsers {
+		m := pat.compiled.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+		info.DeviceFamily = firstNonEmpty(replaceTokens(pat.DeviceReplacement, m), "Other")
+		info.DeviceBrand = replaceTokens(pat.BrandReplacement, m)
+		info.DeviceModel = replaceTokens(pat.ModelReplacement, m)
+		if info.DeviceFamily == "Spider" {
+			info.IsBot = true
+		}
+		break
+	}
+
+	return info
+}
+
+// replaceTokens substitutes uap-core's $1/$2/$3 placeholders in template
+// with the corresponding regex submatch from m, leaving the rest of the
+// template untouched.
+func replaceTokens(template string, m []string) string {
+	if template == "" {
+		return ""
+	}
+	out := template
+	for i := 1; i < len(m); i++ {
+		out = strings.ReplaceAll(out, fmt.Sprintf("$%d", i), m[i])
+	}
+	return out
+}
+
+func group(m []string, idx int) string {
+	if idx < len(m) {
+		return m[idx]
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func joinVersion(v1, v2 string) string {
+	if v1 == "" {
+		return ""
+	}
+	if v2 == "" {
+		return v1
+	}
+	return v1 + "." + v2
+}
@@ -0,0 +1,244 @@
+// Package webhooks fans enriched events out to per-site HTTP subscribers.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	kafkago "github.com/segmentio/kafka-go"
+
+	"tiny-analytics/internal/auth"
+	"tiny-analytics/internal/config"
+	"tiny-analytics/internal/model"
+)
+
+const (
+	eventHeader     = "X-TA-Event"
+	deliveryHeader  = "X-TA-Delivery"
+	signatureHeader = "X-TA-Signature"
+	timestampHeader = "X-TA-Timestamp"
+	nonceHeader     = "X-TA-Nonce"
+
+	// maxConcurrentPerSubscriber bounds how many deliveries to a single
+	// subscriber URL run at once, so one slow endpoint can't starve the
+	// worker pool handling every other subscriber.
+	maxConcurrentPerSubscriber = 4
+	deliveryTimeout            = 10 * time.Second
+	baseBackoff                = 500 * time.Millisecond
+	maxBackoff                 = 30 * time.Second
+)
+
+var (
+	deliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_deliveries_total",
+		Help: "Total webhook delivery attempts, labeled by final status",
+	}, []string{"site", "status"})
+	retriesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_retries_total",
+		Help: "Total webhook delivery retries",
+	})
+	dlqTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_dlq_total",
+		Help: "Total webhook deliveries sent to the dead-letter topic after exhausting retries",
+	})
+	deliveryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "webhook_delivery_latency_seconds",
+		Help:    "Latency of successful webhook delivery attempts, including retries",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+// Dispatcher fans enriched events out to the webhook subscribers configured
+// per site, retrying failed deliveries with backoff before giving up to the
+// dead-letter topic.
+type Dispatcher struct {
+	sites      map[string]config.SiteCredential
+	httpClient *http.Client
+	dlqWriter  *kafkago.Writer
+
+	semMu sync.Mutex
+	sem   map[string]chan struct{}
+}
+
+// New creates a Dispatcher for the given site configuration. dlqWriter
+// should be bound to the webhooks.dlq topic.
+func New(sites map[string]config.SiteCredential, dlqWriter *kafkago.Writer) *Dispatcher {
+	return &Dispatcher{
+		sites:      sites,
+		httpClient: &http.Client{Timeout: deliveryTimeout},
+		dlqWriter:  dlqWriter,
+		sem:        make(map[string]chan struct{}),
+	}
+}
+
+// Dispatch fans an enriched event out to every subscriber of evt.SiteID
+// whose EventNames (if any) include evt.EventName. Each subscriber is
+// delivered to concurrently and independently in the background; Dispatch
+// does not block on delivery completing.
+func (d *Dispatcher) Dispatch(ctx context.Context, evt model.EnrichedEvent, body []byte) {
+	site, ok := d.sites[evt.SiteID]
+	if !ok {
+		return
+	}
+	for _, sub := range site.Webhooks {
+		if !matchesEvent(sub, evt.EventName) {
+			continue
+		}
+		sub := sub
+		go d.deliverWithRetry(ctx, evt.SiteID, sub, evt.EventName, body)
+	}
+}
+
+func matchesEvent(sub config.Webhook, eventName string) bool {
+	if len(sub.EventNames) == 0 {
+		return true
+	}
+	for _, name := range sub.EventNames {
+		if name == eventName {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) deliverWithRetry(ctx context.Context, siteID string, sub config.Webhook, eventName string, body []byte) {
+	sem := d.subscriberSemaphore(sub.URL)
+	sem <- struct{}{}
+	defer func() { <-sem }()
+
+	deliveryID := uuid.NewString()
+	maxRetries := sub.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 5
+	}
+
+	start := time.Now()
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			retriesTotal.Inc()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoffWithJitter(attempt)):
+			}
+		}
+		if err := d.deliverOnce(ctx, siteID, sub, deliveryID, eventName, body); err != nil {
+			lastErr = err
+			continue
+		}
+		deliveriesTotal.WithLabelValues(siteID, "delivered").Inc()
+		deliveryDuration.Observe(time.Since(start).Seconds())
+		return
+	}
+
+	deliveriesTotal.WithLabelValues(siteID, "dlq").Inc()
+	d.sendToDLQ(ctx, siteID, sub, deliveryID, eventName, body, lastErr)
+}
+
+func (d *Dispatcher) deliverOnce(ctx context.Context, siteID string, sub config.Webhook, deliveryID, eventName string, body []byte) error {
+	reqCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(eventHeader, eventName)
+	req.Header.Set(deliveryHeader, deliveryID)
+	if sub.Secret != "" {
+		// The nonce must be unique per attempt, not per delivery: a
+		// subscriber implementing the same (siteID, nonce) replay check as
+		// our own ingest auth (see internal/auth/noncecache) would reject
+		// every retry after the first if it reused deliveryID here.
+		// Retries are deduped on the subscriber side via deliveryID in
+		// X-TA-Delivery instead.
+		nonce := uuid.NewString()
+		ts := time.Now().Unix()
+		sig := auth.ComputeSignature(sub.Secret, siteID, ts, nonce, body)
+		req.Header.Set(timestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(nonceHeader, nonce)
+		req.Header.Set(signatureHeader, sig)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("subscriber returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// dlqRecord is the JSON shape written to the webhooks.dlq topic.
+type dlqRecord struct {
+	DeliveryID string    `json:"delivery_id"`
+	SiteID     string    `json:"site_id"`
+	URL        string    `json:"url"`
+	EventName  string    `json:"event_name"`
+	Payload    []byte    `json:"payload"`
+	LastError  string    `json:"last_error"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+func (d *Dispatcher) sendToDLQ(ctx context.Context, siteID string, sub config.Webhook, deliveryID, eventName string, body []byte, lastErr error) {
+	record := dlqRecord{
+		DeliveryID: deliveryID,
+		SiteID:     siteID,
+		URL:        sub.URL,
+		EventName:  eventName,
+		Payload:    body,
+		FailedAt:   time.Now().UTC(),
+	}
+	if lastErr != nil {
+		record.LastError = lastErr.Error()
+	}
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := d.dlqWriter.WriteMessages(writeCtx, kafkago.Message{
+		Key:   []byte(deliveryID),
+		Value: payload,
+	}); err == nil {
+		dlqTotal.Inc()
+	}
+}
+
+func (d *Dispatcher) subscriberSemaphore(url string) chan struct{} {
+	d.semMu.Lock()
+	defer d.semMu.Unlock()
+	if sem, ok := d.sem[url]; ok {
+		return sem
+	}
+	sem := make(chan struct{}, maxConcurrentPerSubscriber)
+	d.sem[url] = sem
+	return sem
+}
+
+func backoffWithJitter(attempt int) time.Duration {
+	backoff := baseBackoff << uint(attempt-1)
+	if backoff > maxBackoff || backoff <= 0 {
+		backoff = maxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff) / 2))
+	return backoff/2 + jitter
+}
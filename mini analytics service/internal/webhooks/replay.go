@@ -0,0 +1,62 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ErrDeliveryNotFound is returned by ReplayFromDLQ when no dead-lettered
+// message matches the requested delivery ID within the scan window.
+var ErrDeliveryNotFound = errors.New("webhooks: delivery not found in dlq")
+
+// ReplayFromDLQ scans the webhooks.dlq topic from the beginning looking for
+// a record matching deliveryID, and if found, re-dispatches it through d.
+// The scan is bounded by scanTimeout since the DLQ topic has no index by
+// delivery ID.
+func (d *Dispatcher) ReplayFromDLQ(ctx context.Context, brokers []string, topic, deliveryID string, scanTimeout time.Duration) error {
+	reader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		StartOffset: kafkago.FirstOffset,
+		MinBytes:    1,
+		MaxBytes:    10e6,
+	})
+	defer reader.Close()
+
+	scanCtx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+
+	for {
+		m, err := reader.ReadMessage(scanCtx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) {
+				return ErrDeliveryNotFound
+			}
+			return fmt.Errorf("scan dlq: %w", err)
+		}
+		var record dlqRecord
+		if err := json.Unmarshal(m.Value, &record); err != nil {
+			continue
+		}
+		if record.DeliveryID != deliveryID {
+			continue
+		}
+
+		site, ok := d.sites[record.SiteID]
+		if !ok {
+			return fmt.Errorf("replay delivery %s: site %s no longer configured", deliveryID, record.SiteID)
+		}
+		for _, sub := range site.Webhooks {
+			if sub.URL == record.URL {
+				return d.deliverOnce(ctx, record.SiteID, sub, record.DeliveryID, record.EventName, record.Payload)
+			}
+		}
+		return fmt.Errorf("replay delivery %s: subscriber %s no longer configured for site %s", deliveryID, record.URL, record.SiteID)
+	}
+}
@@ -2,6 +2,7 @@ package batcher
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -16,33 +17,120 @@ type Batcher[T any] struct {
 	stop      chan struct{}
 	wg        sync.WaitGroup
 	lastError error
+
+	walDir          string
+	walMaxBytes     int64
+	walSyncPolicy   SyncPolicy
+	walSyncInterval time.Duration
+	wal             *wal[T]
+
+	flushMu          sync.Mutex
+	pendingFlushes   []*flushTicket
+	confirmedThrough int64
+}
+
+// flushTicket tracks one detached batch's WAL byte range until its flushFn
+// call completes. Batches can be in flight concurrently (a size-triggered
+// flush from Add racing the background ticker's Flush), but they must be
+// confirmed, and their bytes truncated, in the same order they were
+// detached: truncating through a later batch's offset before an earlier
+// batch confirms would discard the earlier batch's still-unconfirmed
+// frames too.
+type flushTicket struct {
+	offset    int64
+	confirmed bool
+}
+
+// Option configures optional Batcher behavior. See WithWAL and WithWALSync.
+type Option[T any] func(*Batcher[T])
+
+// WithWAL durably persists every Add to a segmented append-only log under
+// dir before it is acknowledged, so a crash between Add and a successful
+// flush loses nothing: on the next call to New for the same dir, whatever
+// wasn't flushed yet is replayed through flushFn before new items are
+// accepted. maxBytes forces an early flush once the segment grows past it
+// (e.g. because flushFn is stuck retrying a large batch); zero disables
+// that safety valve.
+func WithWAL[T any](dir string, maxBytes int64) Option[T] {
+	return func(b *Batcher[T]) {
+		b.walDir = dir
+		b.walMaxBytes = maxBytes
+	}
 }
 
-// New creates a new batcher instance.
-func New[T any](maxSize int, interval time.Duration, flushFn func([]T) error) *Batcher[T] {
+// WithWALSync overrides the WAL's fsync policy, which defaults to
+// SyncAlways. interval is only used by SyncInterval.
+func WithWALSync[T any](policy SyncPolicy, interval time.Duration) Option[T] {
+	return func(b *Batcher[T]) {
+		b.walSyncPolicy = policy
+		b.walSyncInterval = interval
+	}
+}
+
+// New creates a new batcher instance. If WithWAL was passed, it first
+// replays and flushes any segment left behind by a crashed prior instance
+// before accepting new items.
+func New[T any](maxSize int, interval time.Duration, flushFn func([]T) error, opts ...Option[T]) (*Batcher[T], error) {
 	b := &Batcher[T]{
-		maxSize:  maxSize,
-		interval: interval,
-		flushFn:  flushFn,
-		stop:     make(chan struct{}),
+		maxSize:         maxSize,
+		interval:        interval,
+		flushFn:         flushFn,
+		stop:            make(chan struct{}),
+		walSyncPolicy:   SyncAlways,
+		walSyncInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(b)
 	}
+
+	if b.walDir != "" {
+		w, err := newWAL[T](b.walDir, b.walMaxBytes, b.walSyncPolicy, b.walSyncInterval)
+		if err != nil {
+			return nil, err
+		}
+		b.wal = w
+		unflushed, err := w.replay()
+		if err != nil {
+			return nil, fmt.Errorf("batcher: replay wal: %w", err)
+		}
+		if len(unflushed) > 0 {
+			if err := b.runFlush(unflushed, w.currentSize(), nil); err != nil {
+				return nil, fmt.Errorf("batcher: flush replayed wal segment: %w", err)
+			}
+		}
+	}
+
 	b.wg.Add(1)
 	go b.loop()
-	return b
+	return b, nil
 }
 
-// Add queues an item for batching. If the size threshold is met it flushes immediately.
+// Add queues an item for batching. If a WAL is configured the item is
+// appended (and fsynced, per the sync policy) before Add returns, so a
+// caller can safely acknowledge its own upstream source (e.g. commit a
+// Kafka offset) as soon as Add succeeds. The WAL append and the buffer
+// append happen under the same lock as any concurrent Flush's detach, so a
+// flush can never observe a buffer that is behind the WAL bytes it's about
+// to truncate away. If the size threshold is met it flushes immediately.
 func (b *Batcher[T]) Add(item T) error {
 	b.mu.Lock()
+	if b.wal != nil {
+		if err := b.wal.append(item); err != nil {
+			b.mu.Unlock()
+			return err
+		}
+	}
 	b.buffer = append(b.buffer, item)
-	shouldFlush := len(b.buffer) >= b.maxSize
+	shouldFlush := len(b.buffer) >= b.maxSize || (b.wal != nil && b.wal.exceedsMaxBytes())
 	var batch []T
+	var walOffset int64
+	var ticket *flushTicket
 	if shouldFlush {
-		batch = b.detach()
+		batch, walOffset, ticket = b.detach()
 	}
 	b.mu.Unlock()
 	if shouldFlush {
-		return b.runFlush(batch)
+		return b.runFlush(batch, walOffset, ticket)
 	}
 	return nil
 }
@@ -50,16 +138,23 @@ func (b *Batcher[T]) Add(item T) error {
 // Flush forces a flush of the accumulated items.
 func (b *Batcher[T]) Flush() error {
 	b.mu.Lock()
-	batch := b.detach()
+	batch, walOffset, ticket := b.detach()
 	b.mu.Unlock()
-	return b.runFlush(batch)
+	return b.runFlush(batch, walOffset, ticket)
 }
 
-// Close stops the background ticker and flushes remaining items.
+// Close stops the background ticker, flushes remaining items, and closes
+// the WAL, if any.
 func (b *Batcher[T]) Close() error {
 	close(b.stop)
 	b.wg.Wait()
-	return b.Flush()
+	err := b.Flush()
+	if b.wal != nil {
+		if closeErr := b.wal.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 // LastError returns the last flush error encountered by the background ticker.
@@ -87,22 +182,84 @@ func (b *Batcher[T]) loop() {
 	}
 }
 
-func (b *Batcher[T]) detach() []T {
+// detach swaps out the buffered batch for an empty one and, if a WAL is
+// configured, returns the WAL size at that instant plus a ticket tracking
+// this batch's place in flush order. Because callers always hold b.mu
+// across both the WAL append and the buffer append in Add, that size is
+// exactly the number of WAL bytes attributable to the detached batch —
+// nothing an Add still in flight has written can be included. The ticket
+// is registered in detach order (which, since detach only ever runs under
+// b.mu, is the true order regardless of which flush's flushFn happens to
+// finish first) so runFlush can truncate strictly in that order too.
+func (b *Batcher[T]) detach() ([]T, int64, *flushTicket) {
+	var walOffset int64
+	if b.wal != nil {
+		walOffset = b.wal.currentSize()
+	}
 	if len(b.buffer) == 0 {
-		return nil
+		return nil, walOffset, nil
 	}
 	batch := make([]T, len(b.buffer))
 	copy(batch, b.buffer)
 	b.buffer = b.buffer[:0]
-	return batch
+
+	var ticket *flushTicket
+	if b.wal != nil {
+		ticket = &flushTicket{offset: walOffset}
+		b.flushMu.Lock()
+		b.pendingFlushes = append(b.pendingFlushes, ticket)
+		b.flushMu.Unlock()
+	}
+	return batch, walOffset, ticket
 }
 
-func (b *Batcher[T]) runFlush(batch []T) error {
+func (b *Batcher[T]) runFlush(batch []T, walOffset int64, ticket *flushTicket) error {
 	if len(batch) == 0 {
 		return nil
 	}
 	if b.flushFn == nil {
 		return errors.New("batcher: no flush function configured")
 	}
-	return b.flushFn(batch)
+	if err := b.flushFn(batch); err != nil {
+		return err
+	}
+	// The batch is now durable downstream, so its WAL bytes can be dropped.
+	// A concurrent flush may have detached a later batch and already
+	// finished; confirmThrough only advances the truncation watermark past
+	// this batch's offset once every earlier-detached batch has confirmed
+	// too, so a fast later flush can never wipe an earlier one's
+	// still-unconfirmed frames.
+	if b.wal != nil {
+		if ticket == nil {
+			// Only the pre-loop replay flush in New reaches here, before
+			// any concurrent Add/Flush can race it.
+			return b.wal.truncateThrough(walOffset)
+		}
+		return b.confirmThrough(ticket)
+	}
+	return nil
+}
+
+// confirmThrough marks ticket as flushed and, if it now sits at the front
+// of the pending queue, advances confirmedThrough (and truncates the WAL
+// to match) through every contiguous run of confirmed tickets starting at
+// the front. A ticket confirmed out of order just waits in the queue until
+// the tickets ahead of it confirm.
+func (b *Batcher[T]) confirmThrough(ticket *flushTicket) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	ticket.confirmed = true
+	advanced := false
+	for len(b.pendingFlushes) > 0 && b.pendingFlushes[0].confirmed {
+		front := b.pendingFlushes[0]
+		b.pendingFlushes = b.pendingFlushes[1:]
+		if front.offset > b.confirmedThrough {
+			b.confirmedThrough = front.offset
+			advanced = true
+		}
+	}
+	if !advanced {
+		return nil
+	}
+	return b.wal.truncateThrough(b.confirmedThrough)
 }
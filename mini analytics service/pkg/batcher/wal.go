@@ -0,0 +1,296 @@
+package batcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyncPolicy controls how aggressively a Batcher's WAL calls fsync after
+// appending a frame, trading durability for write latency.
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs after every WAL append. Safest, slowest; the
+	// default.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval fsyncs on a background timer instead of per-append,
+	// bounding data loss on an application crash to the sync interval
+	// instead of eliminating it.
+	SyncInterval
+	// SyncNever relies on the OS to flush dirty pages on its own schedule.
+	// Only appropriate when the WAL's job is surviving a process crash,
+	// not a full machine/power-loss event.
+	SyncNever
+)
+
+// walSegmentName is the single active segment a Batcher's WAL writes to.
+// It holds exactly the frames for the batch currently buffered in memory;
+// it's truncated as soon as that batch is durably flushed downstream, so
+// there's never more than one in-flight batch's worth of data to replay.
+const walSegmentName = "current.wal"
+
+// wal is a length-prefixed, fsync-controlled append log backing a
+// Batcher's in-memory buffer. Every Add is appended here (and, per
+// syncPolicy, fsynced) before the caller's write is acknowledged, so a
+// crash between Add and a successful downstream flush loses nothing: the
+// segment is replayed the next time New is called for the same dir.
+type wal[T any] struct {
+	mu         sync.Mutex
+	maxBytes   int64
+	syncPolicy SyncPolicy
+	file       *os.File
+	size       int64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newWAL[T any](dir string, maxBytes int64, policy SyncPolicy, syncInterval time.Duration) (*wal[T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("batcher: create wal dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, walSegmentName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("batcher: open wal segment: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("batcher: stat wal segment: %w", err)
+	}
+	w := &wal[T]{
+		maxBytes:   maxBytes,
+		syncPolicy: policy,
+		file:       f,
+		size:       info.Size(),
+		stop:       make(chan struct{}),
+	}
+	if policy == SyncInterval {
+		w.wg.Add(1)
+		go w.syncLoop(syncInterval)
+	}
+	return w, nil
+}
+
+// replay decodes every frame currently in the segment, in append order. A
+// truncated final frame (the process died mid-write) is treated as the end
+// of the log rather than an error.
+func (w *wal[T]) replay() ([]T, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("batcher: seek wal segment: %w", err)
+	}
+	r := bufio.NewReader(w.file)
+	var items []T
+	for {
+		var length uint32
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			break
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			break
+		}
+		var item T
+		if err := json.Unmarshal(frame, &item); err != nil {
+			break
+		}
+		items = append(items, item)
+	}
+	if _, err := w.file.Seek(0, io.SeekEnd); err != nil {
+		return nil, fmt.Errorf("batcher: seek wal segment: %w", err)
+	}
+	return items, nil
+}
+
+// append encodes item as a length-prefixed JSON frame and writes it to the
+// segment, fsyncing per syncPolicy. JSON, not gob, is what lets this round-trip
+// an item like model.EnrichedEvent whose Payload/Props fields hold arbitrary
+// decoded-JSON values (nested []interface{}/map[string]interface{} from a
+// caller's ingest body): gob requires every concrete type flowing through an
+// interface{} field to be registered up front, which isn't practical for
+// open-ended user payloads.
+func (w *wal[T]) append(item T) error {
+	buf, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("batcher: encode wal frame: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(buf)))
+	if _, err := w.file.Write(lenBuf[:]); err != nil {
+		return fmt.Errorf("batcher: write wal frame: %w", err)
+	}
+	if _, err := w.file.Write(buf); err != nil {
+		return fmt.Errorf("batcher: write wal frame: %w", err)
+	}
+	w.size += int64(len(lenBuf)) + int64(len(buf))
+	if w.syncPolicy == SyncAlways {
+		if err := w.file.Sync(); err != nil {
+			return fmt.Errorf("batcher: fsync wal: %w", err)
+		}
+	}
+	return nil
+}
+
+// exceedsMaxBytes reports whether the segment has grown past maxBytes,
+// signaling the Batcher should force an immediate flush even though
+// maxSize hasn't been reached yet.
+func (w *wal[T]) exceedsMaxBytes() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.maxBytes > 0 && w.size >= w.maxBytes
+}
+
+// currentSize returns the segment's current byte length.
+func (w *wal[T]) currentSize() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+// truncateThrough discards the first offset bytes of the segment — the
+// frames belonging to a batch that's just been durably flushed downstream —
+// while preserving anything appended after offset was captured. offset
+// comes from currentSize(), taken at the instant the flushed batch was detached
+// from the Batcher's buffer, so bytes past it belong to Adds that raced
+// with the flush and are still sitting in the next batch.
+func (w *wal[T]) truncateThrough(offset int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if offset <= 0 {
+		return nil
+	}
+	if offset >= w.size {
+		if err := w.file.Truncate(0); err != nil {
+			return fmt.Errorf("batcher: truncate wal segment: %w", err)
+		}
+		if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("batcher: seek wal segment: %w", err)
+		}
+		w.size = 0
+		return nil
+	}
+
+	remaining := make([]byte, w.size-offset)
+	if _, err := w.file.ReadAt(remaining, offset); err != nil {
+		return fmt.Errorf("batcher: read wal segment tail: %w", err)
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("batcher: truncate wal segment: %w", err)
+	}
+	if _, err := w.file.WriteAt(remaining, 0); err != nil {
+		return fmt.Errorf("batcher: rewrite wal segment tail: %w", err)
+	}
+	if w.syncPolicy == SyncAlways {
+		if err := w.file.Sync(); err != nil {
+			return fmt.Errorf("batcher: fsync wal segment: %w", err)
+		}
+	}
+	if _, err := w.file.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("batcher: seek wal segment: %w", err)
+	}
+	w.size = int64(len(remaining))
+	return nil
+}
+
+func (w *wal[T]) syncLoop(interval time.Duration) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			_ = w.file.Sync()
+			w.mu.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *wal[T]) Close() error {
+	if w.syncPolicy == SyncInterval {
+		close(w.stop)
+		w.wg.Wait()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// WriteDeadLetter gob-encodes batch as a single length-prefixed record to a
+// new timestamped file under dir, for batches that exhausted their retries
+// downstream. cause is recorded alongside so cmd/wal-tool can surface why a
+// batch was dead-lettered without operators having to guess.
+func WriteDeadLetter[T any](dir string, batch []T, cause error) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("batcher: create dead letter dir: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("deadletter-%d.gob", time.Now().UnixNano()))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("batcher: create dead letter file: %w", err)
+	}
+	defer f.Close()
+
+	record := DeadLetterRecord[T]{Cause: cause.Error(), FailedAt: time.Now().UTC(), Batch: batch}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(record); err != nil {
+		return "", fmt.Errorf("batcher: encode dead letter record: %w", err)
+	}
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(buf.Len()))
+	if _, err := f.Write(lenBuf[:]); err != nil {
+		return "", fmt.Errorf("batcher: write dead letter record: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return "", fmt.Errorf("batcher: write dead letter record: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return "", fmt.Errorf("batcher: fsync dead letter file: %w", err)
+	}
+	return path, nil
+}
+
+// DeadLetterRecord is the on-disk shape WriteDeadLetter persists and
+// ReadDeadLetter parses back, one per file.
+type DeadLetterRecord[T any] struct {
+	Cause    string
+	FailedAt time.Time
+	Batch    []T
+}
+
+// ReadDeadLetter decodes a single dead-letter file written by
+// WriteDeadLetter.
+func ReadDeadLetter[T any](path string) (DeadLetterRecord[T], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DeadLetterRecord[T]{}, fmt.Errorf("batcher: read dead letter file: %w", err)
+	}
+	if len(data) < 4 {
+		return DeadLetterRecord[T]{}, fmt.Errorf("batcher: dead letter file %s is truncated", path)
+	}
+	length := binary.BigEndian.Uint32(data[:4])
+	if int(length) > len(data)-4 {
+		return DeadLetterRecord[T]{}, fmt.Errorf("batcher: dead letter file %s is truncated", path)
+	}
+	var record DeadLetterRecord[T]
+	if err := gob.NewDecoder(bytes.NewReader(data[4 : 4+length])).Decode(&record); err != nil {
+		return DeadLetterRecord[T]{}, fmt.Errorf("batcher: decode dead letter record: %w", err)
+	}
+	return record, nil
+}
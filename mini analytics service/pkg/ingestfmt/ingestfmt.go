@@ -0,0 +1,20 @@
+// Package ingestfmt translates third-party ingest wire formats (GA4
+// Measurement Protocol, OTLP logs, ...) into the internal model.Event shape
+// the rest of the pipeline understands, so the ingest API can accept more
+// than its own native JSON payload without every decoder reimplementing
+// site resolution, batching, or Kafka production.
+package ingestfmt
+
+import "tiny-analytics/internal/model"
+
+// Decoder translates one request body in a foreign format into zero or
+// more model.Events. Implementations are stateless and safe for concurrent
+// use.
+type Decoder interface {
+	// Name identifies the format for metrics and logging, e.g. "ga4" or
+	// "otlp_logs".
+	Name() string
+	// Decode parses body into the events it contains. An empty result with
+	// a nil error means the body was well-formed but carried no events.
+	Decode(body []byte) ([]model.Event, error)
+}
@@ -0,0 +1,112 @@
+package ingestfmt
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"tiny-analytics/internal/model"
+)
+
+// Resource/log attribute keys OTLPLogsDecoder looks for. Any other
+// attributes on a log record are carried through to model.Event.Props.
+const (
+	attrSiteID    = "ta.site_id"
+	attrEventName = "ta.event_name"
+)
+
+// OTLPLogsDecoder decodes an OTLP/HTTP logs export
+// (POST /v1/logs, application/x-protobuf body of an
+// ExportLogsServiceRequest) into model.Events. The exporting resource
+// carries the site via the ta.site_id attribute; each log record becomes
+// one event, named by its ta.event_name attribute (falling back to the log
+// body, for exporters that don't set custom attributes) with every other
+// attribute copied into Props.
+type OTLPLogsDecoder struct{}
+
+func (OTLPLogsDecoder) Name() string { return "otlp_logs" }
+
+// Decode unmarshals body as an ExportLogsServiceRequest and flattens every
+// log record across every resource/scope into a model.Event.
+func (OTLPLogsDecoder) Decode(body []byte) ([]model.Event, error) {
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("ingestfmt: decode otlp logs request: %w", err)
+	}
+
+	var events []model.Event
+	for _, rl := range req.GetResourceLogs() {
+		resourceAttrs := attrMap(rl.GetResource().GetAttributes())
+		siteID, _ := resourceAttrs[attrSiteID].(string)
+		for _, sl := range rl.GetScopeLogs() {
+			for _, rec := range sl.GetLogRecords() {
+				events = append(events, logRecordToEvent(siteID, resourceAttrs, rec))
+			}
+		}
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("ingestfmt: otlp logs request has no log records")
+	}
+	return events, nil
+}
+
+func logRecordToEvent(siteID string, resourceAttrs map[string]any, rec *otlpLogRecord) model.Event {
+	recordAttrs := attrMap(rec.GetAttributes())
+
+	eventName, _ := recordAttrs[attrEventName].(string)
+	if eventName == "" {
+		eventName = rec.GetBody().GetStringValue()
+	}
+
+	props := make(map[string]any, len(resourceAttrs)+len(recordAttrs))
+	for k, v := range resourceAttrs {
+		props[k] = v
+	}
+	for k, v := range recordAttrs {
+		props[k] = v
+	}
+	delete(props, attrSiteID)
+	delete(props, attrEventName)
+
+	return model.Event{
+		SiteID:    siteID,
+		EventName: eventName,
+		TS:        int64(rec.GetTimeUnixNano() / 1_000_000),
+		Props:     props,
+	}
+}
+
+type otlpLogRecord = logspb.LogRecord
+
+// attrMap flattens OTLP KeyValue attributes into a plain map, unwrapping
+// each AnyValue to the Go type it holds. Array/KeyValueList/Bytes values
+// are skipped; this decoder only needs the scalar attributes analytics
+// events carry.
+func attrMap(attrs []*commonpb.KeyValue) map[string]any {
+	out := make(map[string]any, len(attrs))
+	for _, kv := range attrs {
+		if v, ok := anyValue(kv.GetValue()); ok {
+			out[kv.GetKey()] = v
+		}
+	}
+	return out
+}
+
+func anyValue(v *commonpb.AnyValue) (any, bool) {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue, true
+	case *commonpb.AnyValue_BoolValue:
+		return val.BoolValue, true
+	case *commonpb.AnyValue_IntValue:
+		return val.IntValue, true
+	case *commonpb.AnyValue_DoubleValue:
+		return val.DoubleValue, true
+	default:
+		return nil, false
+	}
+}
@@ -82,6 +82,42 @@ func TestEndToEndPipeline(t *testing.T) {
 	require.NotEmpty(t, series)
 }
 
+// BenchmarkPageviewsRollupVsRaw compares the events_daily_site rollup path
+// against a forced raw-table scan for a historical (non-today) date range.
+// Run against the docker-compose stack with:
+//
+//	go test -tags e2e -run NONE -bench Pageviews ./test/integration/...
+func BenchmarkPageviewsRollupVsRaw(b *testing.B) {
+	dsn := os.Getenv("CLICKHOUSE_DSN")
+	if dsn == "" {
+		b.Skip("set CLICKHOUSE_DSN to run against a live ClickHouse instance")
+	}
+	client, err := ch.New(context.Background(), dsn)
+	if err != nil {
+		b.Fatalf("clickhouse: %v", err)
+	}
+	defer client.Close()
+
+	siteID := "site-1"
+	to := time.Now().UTC().AddDate(0, 0, -1)
+	from := to.AddDate(0, 0, -30)
+
+	b.Run("rollup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := client.Pageviews(context.Background(), siteID, from, to, false); err != nil {
+				b.Fatalf("pageviews (rollup): %v", err)
+			}
+		}
+	})
+	b.Run("raw", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := client.Pageviews(context.Background(), siteID, from, to, true); err != nil {
+				b.Fatalf("pageviews (raw): %v", err)
+			}
+		}
+	})
+}
+
 func runCommand(t *testing.T, name string, args ...string) {
 	t.Helper()
 	cmd := exec.Command(name, args...)
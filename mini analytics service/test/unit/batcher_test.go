@@ -1,12 +1,17 @@
 package unit
 
 import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"tiny-analytics/internal/model"
 	"tiny-analytics/pkg/batcher"
 )
 
@@ -15,13 +20,14 @@ func TestBatcherFlushBySize(t *testing.T) {
 		mu      sync.Mutex
 		flushed [][]int
 	)
-	b := batcher.New[int](3, time.Second, func(items []int) error {
+	b, err := batcher.New[int](3, time.Second, func(items []int) error {
 		mu.Lock()
 		defer mu.Unlock()
 		cp := append([]int(nil), items...)
 		flushed = append(flushed, cp)
 		return nil
 	})
+	require.NoError(t, err)
 	defer b.Close()
 
 	require.NoError(t, b.Add(1))
@@ -40,12 +46,13 @@ func TestBatcherFlushByInterval(t *testing.T) {
 		mu      sync.Mutex
 		flushed int
 	)
-	b := batcher.New[int](10, 50*time.Millisecond, func(items []int) error {
+	b, err := batcher.New[int](10, 50*time.Millisecond, func(items []int) error {
 		mu.Lock()
 		defer mu.Unlock()
 		flushed += len(items)
 		return nil
 	})
+	require.NoError(t, err)
 	defer b.Close()
 
 	require.NoError(t, b.Add(42))
@@ -56,3 +63,208 @@ func TestBatcherFlushByInterval(t *testing.T) {
 		return flushed == 1
 	}, time.Second, 20*time.Millisecond)
 }
+
+func TestBatcherWALTruncatesAfterFlush(t *testing.T) {
+	walDir := filepath.Join(t.TempDir(), "wal")
+	b, err := batcher.New[int](2, time.Hour, func(items []int) error {
+		return nil
+	}, batcher.WithWAL[int](walDir, 0))
+	require.NoError(t, err)
+	defer b.Close()
+
+	require.NoError(t, b.Add(1))
+	require.NoError(t, b.Add(2)) // hits maxSize, flushes, truncates the WAL
+
+	info, err := os.Stat(filepath.Join(walDir, "current.wal"))
+	require.NoError(t, err)
+	require.Zero(t, info.Size(), "WAL segment should be truncated once its batch is durably flushed")
+}
+
+// TestBatcherWALPreservesConcurrentAddDuringFlush reproduces the crash
+// window where a Flush (here triggered manually, standing in for the
+// background ticker) has already detached its batch and is blocked in
+// flushFn while an Add races in behind it. The WAL frame for that racing
+// Add must survive the flush's truncate even though it lands mid-flush.
+func TestBatcherWALPreservesConcurrentAddDuringFlush(t *testing.T) {
+	walDir := filepath.Join(t.TempDir(), "wal")
+	flushStarted := make(chan struct{})
+	releaseFlush := make(chan struct{})
+
+	b, err := batcher.New[string](1000, time.Hour, func(items []string) error {
+		close(flushStarted)
+		<-releaseFlush
+		return nil
+	}, batcher.WithWAL[string](walDir, 0))
+	require.NoError(t, err)
+
+	require.NoError(t, b.Add("a"))
+
+	flushDone := make(chan error, 1)
+	go func() { flushDone <- b.Flush() }()
+	<-flushStarted
+
+	require.NoError(t, b.Add("b")) // races with the in-flight flush's truncate
+	close(releaseFlush)
+	require.NoError(t, <-flushDone)
+
+	info, err := os.Stat(filepath.Join(walDir, "current.wal"))
+	require.NoError(t, err)
+	require.NotZero(t, info.Size(), "WAL must still hold b's frame after a's flush truncates, not be wiped entirely")
+}
+
+// TestBatcherWALDoesNotTruncatePastEarlierUnconfirmedFlush reproduces the
+// race where a later-detached batch's flushFn finishes before an earlier,
+// still-in-flight batch's flushFn returns. The earlier batch's frame must
+// survive until it confirms, even though the later batch's larger WAL
+// offset would otherwise cover (and wipe) it.
+func TestBatcherWALDoesNotTruncatePastEarlierUnconfirmedFlush(t *testing.T) {
+	walDir := filepath.Join(t.TempDir(), "wal")
+	aStarted := make(chan struct{})
+	releaseA := make(chan struct{})
+
+	b, err := batcher.New[string](3, time.Hour, func(items []string) error {
+		if len(items) == 1 && items[0] == "a" {
+			close(aStarted)
+			<-releaseA
+		}
+		return nil
+	}, batcher.WithWAL[string](walDir, 0))
+	require.NoError(t, err)
+
+	require.NoError(t, b.Add("a"))
+	aFlushDone := make(chan error, 1)
+	go func() { aFlushDone <- b.Flush() }()
+	<-aStarted
+
+	// Crosses maxSize and flushes synchronously inside Add, well before
+	// a's flushFn returns.
+	require.NoError(t, b.Add("b"))
+	require.NoError(t, b.Add("c"))
+	require.NoError(t, b.Add("d"))
+
+	info, err := os.Stat(filepath.Join(walDir, "current.wal"))
+	require.NoError(t, err)
+	require.NotZero(t, info.Size(), "a's unconfirmed frame must not be truncated just because d's flush, detached later, finished first")
+
+	close(releaseA)
+	require.NoError(t, <-aFlushDone)
+
+	info, err = os.Stat(filepath.Join(walDir, "current.wal"))
+	require.NoError(t, err)
+	require.Zero(t, info.Size(), "WAL should be fully truncated once both batches are confirmed")
+}
+
+// TestBatcherWALRoundTripsNestedPayload guards against WAL frames being
+// gob-encoded: gob requires every concrete type reachable through an
+// interface{} field to be registered up front, which real ingest payloads
+// (e.g. a GA4 ecommerce event's nested "items" array) never are. JSON
+// encoding has no such requirement.
+func TestBatcherWALRoundTripsNestedPayload(t *testing.T) {
+	walDir := filepath.Join(t.TempDir(), "wal")
+	evt := model.EnrichedEvent{
+		SiteID:    "site-1",
+		EventName: "purchase",
+		Payload: map[string]any{
+			"total": 42.5,
+			"items": []any{
+				map[string]any{"sku": "abc", "qty": 2.0},
+				map[string]any{"sku": "def", "qty": 1.0},
+			},
+		},
+	}
+
+	b, err := batcher.New[model.EnrichedEvent](10, time.Hour, func([]model.EnrichedEvent) error {
+		return nil
+	}, batcher.WithWAL[model.EnrichedEvent](walDir, 0))
+	require.NoError(t, err)
+	require.NoError(t, b.Add(evt), "Add must durably WAL an event whose Payload holds nested map/slice values")
+
+	// Simulate a crash before this batch is ever flushed: a second New for
+	// the same dir should replay and deliver evt unchanged.
+	var recovered []model.EnrichedEvent
+	b2, err := batcher.New[model.EnrichedEvent](10, time.Hour, func(items []model.EnrichedEvent) error {
+		recovered = append(recovered, items...)
+		return nil
+	}, batcher.WithWAL[model.EnrichedEvent](walDir, 0))
+	require.NoError(t, err)
+	defer b2.Close()
+
+	require.Equal(t, []model.EnrichedEvent{evt}, recovered)
+}
+
+func TestBatcherWALDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	batch := []int{1, 2, 3}
+	cause := errors.New("clickhouse: insert failed")
+
+	path, err := batcher.WriteDeadLetter(dir, batch, cause)
+	require.NoError(t, err)
+
+	record, err := batcher.ReadDeadLetter[int](path)
+	require.NoError(t, err)
+	require.Equal(t, batch, record.Batch)
+	require.Equal(t, cause.Error(), record.Cause)
+}
+
+// walCrashHelperItems is what TestBatcherWALCrashRecovery's subprocess adds
+// to its batcher before being killed mid-batch.
+var walCrashHelperItems = []string{"evt-0", "evt-1", "evt-2", "evt-3", "evt-4"}
+
+// TestBatcherWALCrashRecovery spawns a subprocess that durably Adds items
+// to a WAL-backed Batcher and never flushes them, kills it mid-batch (as a
+// real process crash would be), and asserts that re-opening the same WAL
+// dir replays and flushes every item with zero loss.
+func TestBatcherWALCrashRecovery(t *testing.T) {
+	if os.Getenv("TA_BATCHER_WAL_CRASH_HELPER") == "1" {
+		runWALCrashHelper(t)
+		select {} // stay alive until the parent kills us
+	}
+
+	walDir := filepath.Join(t.TempDir(), "wal")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestBatcherWALCrashRecovery$")
+	cmd.Env = append(os.Environ(), "TA_BATCHER_WAL_CRASH_HELPER=1", "TA_BATCHER_WAL_DIR="+walDir)
+	require.NoError(t, cmd.Start())
+
+	// Give the helper time to Add (and fsync) every item before it's
+	// killed; it never calls Close, so nothing gets flushed downstream.
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(filepath.Join(walDir, "current.wal"))
+		return err == nil && info.Size() > 0
+	}, 2*time.Second, 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, cmd.Process.Kill())
+	_ = cmd.Wait()
+
+	var (
+		mu        sync.Mutex
+		recovered []string
+	)
+	b, err := batcher.New[string](1000, time.Hour, func(items []string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		recovered = append(recovered, items...)
+		return nil
+	}, batcher.WithWAL[string](walDir, 0))
+	require.NoError(t, err)
+	defer b.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.ElementsMatch(t, walCrashHelperItems, recovered, "every item Added before the crash must be replayed with zero loss")
+}
+
+func runWALCrashHelper(t *testing.T) {
+	dir := os.Getenv("TA_BATCHER_WAL_DIR")
+	b, err := batcher.New[string](1000, time.Hour, func([]string) error {
+		return nil
+	}, batcher.WithWAL[string](dir, 0))
+	if err != nil {
+		t.Fatalf("helper: new batcher: %v", err)
+	}
+	for _, item := range walCrashHelperItems {
+		if err := b.Add(item); err != nil {
+			t.Fatalf("helper: add: %v", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"tiny-analytics/internal/auth"
+	"tiny-analytics/internal/httpx"
+)
+
+// decompressResponse mirrors the handler's gin.H so tests can assert on the
+// decoded body field instead of the response's raw, JSON-escaped text.
+type decompressResponse struct {
+	Body string `json:"body"`
+	Sig  string `json:"sig"`
+}
+
+func newDecompressRouter(maxBytes int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(httpx.DecompressMiddleware(maxBytes))
+	router.POST("/v1/collect", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "read failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"body": string(body),
+			"sig":  auth.ComputeSignature("secret", "site-1", 1, "nonce", body),
+		})
+	})
+	return router
+}
+
+func gzipBody(t *testing.T, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(plain)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func deflateBody(t *testing.T, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	require.NoError(t, err)
+	_, err = w.Write(plain)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecompressMiddlewareGzip(t *testing.T) {
+	router := newDecompressRouter(1024)
+	plain := []byte(`{"site_id":"site-1"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/collect", bytes.NewReader(gzipBody(t, plain)))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp decompressResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, string(plain), resp.Body)
+}
+
+func TestDecompressMiddlewareDeflate(t *testing.T) {
+	router := newDecompressRouter(1024)
+	plain := []byte(`{"site_id":"site-1"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/collect", bytes.NewReader(deflateBody(t, plain)))
+	req.Header.Set("Content-Encoding", "deflate")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp decompressResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, string(plain), resp.Body)
+}
+
+func TestDecompressMiddlewareOversize(t *testing.T) {
+	router := newDecompressRouter(4)
+	plain := []byte(`{"site_id":"site-1"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/collect", bytes.NewReader(gzipBody(t, plain)))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestDecompressMiddlewareMalformed(t *testing.T) {
+	router := newDecompressRouter(1024)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/collect", bytes.NewReader([]byte("not gzip")))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDecompressMiddlewareUnsupportedEncoding(t *testing.T) {
+	router := newDecompressRouter(1024)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/collect", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Encoding", "br")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
+
+func TestDecompressMiddlewareSignatureOverDecodedBody(t *testing.T) {
+	router := newDecompressRouter(1024)
+	plain := []byte(`{"site_id":"site-1"}`)
+	expectedSig := auth.ComputeSignature("secret", "site-1", 1, "nonce", plain)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/collect", bytes.NewReader(gzipBody(t, plain)))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), expectedSig)
+}
@@ -26,7 +26,7 @@ func TestEnrichAddsMetadata(t *testing.T) {
 		UA: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/90.0",
 	}
 
-	enriched, err := pipeline.Enrich(raw, "salt")
+	enriched, err := pipeline.Enrich(raw, "salt", pipeline.NoopGeoResolver{})
 	require.NoError(t, err)
 	require.Equal(t, "ads", enriched.UTMSource)
 	require.Equal(t, "cpc", enriched.UTMMedium)
@@ -37,4 +37,29 @@ func TestEnrichAddsMetadata(t *testing.T) {
 	require.Len(t, enriched.IPHash, 64)
 	require.Equal(t, raw.Referrer, enriched.Referrer)
 	require.Equal(t, raw.SiteID, enriched.SiteID)
+	require.Equal(t, "unknown", enriched.Country)
+}
+
+func TestEnrichUsesGeoResolver(t *testing.T) {
+	raw := model.RawEvent{
+		Event: model.Event{
+			SiteID:    "site-1",
+			EventName: "pageview",
+			URL:       "https://example.com/",
+			TS:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli(),
+		},
+		IP: "203.0.113.5",
+		UA: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/90.0",
+	}
+
+	geo := pipeline.NewStubGeoResolver(map[string]pipeline.GeoInfo{
+		"203.0.113.5": {Country: "US", Region: "CA", City: "San Francisco", ASN: "AS15169"},
+	}, pipeline.GeoInfo{Country: "unknown"})
+
+	enriched, err := pipeline.Enrich(raw, "salt", geo)
+	require.NoError(t, err)
+	require.Equal(t, "US", enriched.Country)
+	require.Equal(t, "CA", enriched.Region)
+	require.Equal(t, "San Francisco", enriched.City)
+	require.Equal(t, "AS15169", enriched.ASN)
 }
@@ -1,18 +1,105 @@
 package unit
 
 import (
+	"net/http"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"tiny-analytics/internal/auth"
+	"tiny-analytics/internal/auth/noncecache"
 )
 
 func TestHMACVerify(t *testing.T) {
 	secret := "super-secret"
+	siteID := "site-1"
 	body := []byte(`{"hello":"world"}`)
+	ts := time.Now().Unix()
+	nonce := "nonce-1"
 
-	sig := auth.ComputeSignature(secret, body)
-	require.True(t, auth.VerifySignature(secret, body, sig))
-	require.False(t, auth.VerifySignature(secret, body, "deadbeef"))
+	sig := auth.ComputeSignature(secret, siteID, ts, nonce, body)
+	require.True(t, auth.VerifySignature(secret, siteID, ts, nonce, body, sig))
+	require.False(t, auth.VerifySignature(secret, siteID, ts, nonce, body, "deadbeef"))
+}
+
+func TestHMACVerifySiteIsolation(t *testing.T) {
+	secret := "super-secret"
+	body := []byte(`{"hello":"world"}`)
+	ts := time.Now().Unix()
+	nonce := "nonce-1"
+
+	sig := auth.ComputeSignature(secret, "site-1", ts, nonce, body)
+	require.False(t, auth.VerifySignature(secret, "site-2", ts, nonce, body, sig),
+		"a signature minted for one site must not verify for another")
+}
+
+func TestHMACVerifyLegacy(t *testing.T) {
+	secret := "super-secret"
+	body := []byte(`{"hello":"world"}`)
+
+	sig := auth.ComputeSignature(secret, "site-1", 0, "", body)
+	require.False(t, auth.VerifySignatureLegacy(secret, body, sig), "legacy scheme does not include the site/timestamp/nonce placeholders")
+}
+
+func TestVerifySigned(t *testing.T) {
+	secret := "super-secret"
+	siteID := "site-1"
+	body := []byte(`{"hello":"world"}`)
+	now := time.Now()
+	nonces := noncecache.New(time.Minute, 100, time.Hour)
+	defer nonces.Close()
+
+	ts := now.Unix()
+	nonce := "nonce-1"
+	sig := auth.ComputeSignature(secret, siteID, ts, nonce, body)
+
+	require.NoError(t, auth.VerifySigned(secret, siteID, body, ts, nonce, sig, now, 5*time.Minute, nonces))
+
+	err := auth.VerifySigned(secret, siteID, body, ts, nonce, sig, now, 5*time.Minute, nonces)
+	require.ErrorIs(t, err, auth.ErrNonceReplayed)
+
+	expiredTS := now.Add(-time.Hour).Unix()
+	expiredSig := auth.ComputeSignature(secret, siteID, expiredTS, "nonce-2", body)
+	err = auth.VerifySigned(secret, siteID, body, expiredTS, "nonce-2", expiredSig, now, 5*time.Minute, nonces)
+	require.ErrorIs(t, err, auth.ErrTimestampExpired)
+
+	err = auth.VerifySigned(secret, siteID, body, ts, "nonce-3", "deadbeef", now, 5*time.Minute, nonces)
+	require.ErrorIs(t, err, auth.ErrInvalidSignature)
+}
+
+func TestVerifyRequest(t *testing.T) {
+	secret := "super-secret"
+	siteID := "site-1"
+	body := []byte(`{"hello":"world"}`)
+	now := time.Now()
+	nonces := noncecache.New(time.Minute, 100, time.Hour)
+	defer nonces.Close()
+	cfg := auth.RequestConfig{SiteID: siteID, Secret: secret, Skew: 5 * time.Minute, Now: now, Nonces: nonces}
+
+	sign := func(site string, ts int64, nonce string) http.Header {
+		h := make(http.Header)
+		h.Set(auth.TimestampHeader, strconv.FormatInt(ts, 10))
+		h.Set(auth.NonceHeader, nonce)
+		h.Set(auth.SignatureHeader, auth.ComputeSignature(secret, site, ts, nonce, body))
+		return h
+	}
+
+	require.NoError(t, auth.VerifyRequest(sign(siteID, now.Unix(), "nonce-1"), body, cfg))
+
+	// Replay of the same (siteID, nonce) is rejected.
+	err := auth.VerifyRequest(sign(siteID, now.Unix(), "nonce-1"), body, cfg)
+	require.ErrorIs(t, err, auth.ErrNonceReplayed)
+
+	// A timestamp outside the skew window is rejected.
+	err = auth.VerifyRequest(sign(siteID, now.Add(-time.Hour).Unix(), "nonce-2"), body, cfg)
+	require.ErrorIs(t, err, auth.ErrTimestampExpired)
+
+	// Missing headers are rejected outright.
+	require.ErrorIs(t, auth.VerifyRequest(make(http.Header), body, cfg), auth.ErrMissingHeaders)
+
+	// A signature minted for a different site is rejected for this one.
+	err = auth.VerifyRequest(sign("site-2", now.Unix(), "nonce-3"), body, cfg)
+	require.ErrorIs(t, err, auth.ErrInvalidSignature)
 }
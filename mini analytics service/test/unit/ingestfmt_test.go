@@ -0,0 +1,136 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"tiny-analytics/pkg/ingestfmt"
+)
+
+// This payload mirrors what the gtag.js / GA4 App+Web SDKs POST to
+// https://www.google-analytics.com/mp/collect.
+const ga4ConformancePayload = `{
+	"client_id": "123456789.987654321",
+	"user_id": "user-42",
+	"events": [
+		{
+			"name": "page_view",
+			"params": {
+				"session_id": "sess-1",
+				"page_location": "https://example.com/pricing",
+				"page_referrer": "https://example.com/",
+				"engagement_time_msec": 100
+			}
+		},
+		{
+			"name": "purchase",
+			"params": {
+				"session_id": "sess-1",
+				"currency": "USD",
+				"value": 49.99
+			}
+		}
+	]
+}`
+
+func TestGA4DecoderConformance(t *testing.T) {
+	events, err := ingestfmt.GA4Decoder{}.Decode([]byte(ga4ConformancePayload))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	pv := events[0]
+	require.Equal(t, "page_view", pv.EventName)
+	require.Equal(t, "user-42", pv.UserID)
+	require.Equal(t, "sess-1", pv.SessionID)
+	require.Equal(t, "https://example.com/pricing", pv.URL)
+	require.Equal(t, "https://example.com/", pv.Referrer)
+	require.Equal(t, float64(100), pv.Props["engagement_time_msec"])
+
+	purchase := events[1]
+	require.Equal(t, "purchase", purchase.EventName)
+	require.Equal(t, "USD", purchase.Props["currency"])
+	require.Equal(t, 49.99, purchase.Props["value"])
+}
+
+func TestGA4DecoderFallsBackToClientID(t *testing.T) {
+	events, err := ingestfmt.GA4Decoder{}.Decode([]byte(`{
+		"client_id": "anon-1",
+		"events": [{"name": "page_view", "params": {}}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, "anon-1", events[0].UserID)
+}
+
+func TestGA4DecoderRejectsEmptyEvents(t *testing.T) {
+	_, err := ingestfmt.GA4Decoder{}.Decode([]byte(`{"client_id": "c1", "events": []}`))
+	require.Error(t, err)
+}
+
+func TestOTLPLogsDecoderConformance(t *testing.T) {
+	req := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{
+						strAttr("ta.site_id", "site-1"),
+						strAttr("service.name", "otel-collector"),
+					},
+				},
+				ScopeLogs: []*logspb.ScopeLogs{
+					{
+						LogRecords: []*logspb.LogRecord{
+							{
+								TimeUnixNano: 1_700_000_000_000_000_000,
+								Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "fallback body"}},
+								Attributes: []*commonpb.KeyValue{
+									strAttr("ta.event_name", "checkout_completed"),
+									strAttr("http.method", "POST"),
+								},
+							},
+							{
+								TimeUnixNano: 1_700_000_001_000_000_000,
+								Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "log line"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	body, err := proto.Marshal(req)
+	require.NoError(t, err)
+
+	events, err := ingestfmt.OTLPLogsDecoder{}.Decode(body)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	require.Equal(t, "site-1", events[0].SiteID)
+	require.Equal(t, "checkout_completed", events[0].EventName)
+	require.Equal(t, "POST", events[0].Props["http.method"])
+	require.Equal(t, "otel-collector", events[0].Props["service.name"])
+	require.Equal(t, int64(1_700_000_000_000), events[0].TS)
+
+	require.Equal(t, "log line", events[1].EventName, "falls back to the log body when ta.event_name is unset")
+}
+
+func TestOTLPLogsDecoderRejectsEmptyExport(t *testing.T) {
+	body, err := proto.Marshal(&collogspb.ExportLogsServiceRequest{})
+	require.NoError(t, err)
+
+	_, err = ingestfmt.OTLPLogsDecoder{}.Decode(body)
+	require.Error(t, err)
+}
+
+func strAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
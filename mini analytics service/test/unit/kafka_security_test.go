@@ -0,0 +1,100 @@
+package unit
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	ikafka "tiny-analytics/internal/kafka"
+)
+
+// TestKafkaReaderPlaintextDialsDirectly asserts that a reader configured for
+// plaintext never attempts a TLS handshake against the broker.
+func TestKafkaReaderPlaintextDialsDirectly(t *testing.T) {
+	ln, accepted := newPlainStubListener(t)
+	defer ln.Close()
+
+	reader, err := ikafka.NewReader([]string{ln.Addr().String()}, "topic", "group", ikafka.Options{Protocol: "plaintext"})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	select {
+	case raw := <-accepted:
+		buf := make([]byte, 1)
+		raw.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := raw.Read(buf)
+		// The client speaks first either way (kafka-go's reader sends its
+		// initial request immediately), so plaintext can't be told apart
+		// from TLS by whether bytes arrive at all — only by their shape. A
+		// TLS ClientHello's first byte is the handshake record type 0x16;
+		// a plaintext Kafka request starts with its length prefix, which
+		// cannot be that value for any request this stub will receive.
+		require.NoError(t, err)
+		require.NotEqual(t, byte(0x16), buf[0], "expected a plaintext Kafka request, got a TLS handshake record: %v", buf[:n])
+	case <-time.After(time.Second):
+		t.Fatal("stub listener never accepted a connection")
+	}
+}
+
+// TestKafkaReaderTLSAttemptsHandshake asserts that a reader configured for
+// TLS opens a TLS handshake against the broker.
+func TestKafkaReaderTLSAttemptsHandshake(t *testing.T) {
+	ln, accepted := newPlainStubListener(t)
+	defer ln.Close()
+
+	reader, err := ikafka.NewReader([]string{ln.Addr().String()}, "topic", "group", ikafka.Options{
+		Protocol:           "tls",
+		InsecureSkipVerify: true,
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	select {
+	case raw := <-accepted:
+		buf := make([]byte, 3)
+		raw.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := raw.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, byte(0x16), buf[0], "expected a TLS handshake record, got %v", buf[:n])
+	case <-time.After(2 * time.Second):
+		t.Fatal("stub listener never accepted a connection")
+	}
+}
+
+// TestKafkaReaderRejectsBadCAFile asserts that a reader fails to build
+// rather than silently falling back to an unauthenticated plaintext dialer
+// when the configured CA file doesn't exist.
+func TestKafkaReaderRejectsBadCAFile(t *testing.T) {
+	_, err := ikafka.NewReader([]string{"127.0.0.1:0"}, "topic", "group", ikafka.Options{
+		Protocol: "tls",
+		CAFile:   "/nonexistent/ca.pem",
+	})
+	require.Error(t, err)
+}
+
+// TestKafkaWriterRejectsUnsupportedSASLMechanism asserts that a writer
+// fails to build rather than silently falling back to plaintext when the
+// configured SASL mechanism isn't one this service supports.
+func TestKafkaWriterRejectsUnsupportedSASLMechanism(t *testing.T) {
+	_, err := ikafka.NewWriter([]string{"127.0.0.1:0"}, "topic", ikafka.Options{
+		Protocol:      "sasl_ssl",
+		SASLMechanism: "gssapi",
+	})
+	require.Error(t, err)
+}
+
+func newPlainStubListener(t *testing.T) (net.Listener, <-chan net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	return ln, accepted
+}
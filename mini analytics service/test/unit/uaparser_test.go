@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tiny-analytics/internal/util"
+)
+
+func TestParseUADistinguishesBrowsersSharingUAFragments(t *testing.T) {
+	cases := []struct {
+		name    string
+		ua      string
+		family  string
+		osFam   string
+		device  string
+		isBot   bool
+	}{
+		{
+			name:   "safari desktop is not misread as chrome",
+			ua:     "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Safari/605.1.15",
+			family: "Safari",
+			osFam:  "Mac OS X",
+			device: "Other",
+		},
+		{
+			name:   "chrome on windows",
+			ua:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36",
+			family: "Chrome",
+			osFam:  "Windows",
+			device: "Other",
+		},
+		{
+			name:   "edge is not masked by the chrome token it carries",
+			ua:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36 Edg/115.0.1901.183",
+			family: "Edge",
+			osFam:  "Windows",
+			device: "Other",
+		},
+		{
+			name:   "ipados reports its own family, not macos",
+			ua:     "Mozilla/5.0 (iPad; CPU OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Mobile/15E148 Safari/604.1",
+			family: "Mobile Safari",
+			osFam:  "iOS",
+			device: "iPad",
+		},
+		{
+			name:   "googlebot is classified as a bot",
+			ua:     "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			family: "Googlebot",
+			osFam:  "Other",
+			device: "Spider",
+			isBot:  true,
+		},
+		{
+			name:   "vivaldi is not misread as chrome",
+			ua:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36 Vivaldi/6.1",
+			family: "Vivaldi",
+			osFam:  "Windows",
+			device: "Other",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := util.ParseUA(tc.ua)
+			require.Equal(t, tc.family, info.Family)
+			require.Equal(t, tc.osFam, info.OSFamily)
+			require.Equal(t, tc.device, info.DeviceFamily)
+			require.Equal(t, tc.isBot, info.IsBot)
+		})
+	}
+}
+
+func TestIsBotCombinesDenylistAndUAPCore(t *testing.T) {
+	require.True(t, util.IsBot("Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", nil))
+	require.True(t, util.IsBot("SomeCustomScraper/1.0", []string{"scraper"}))
+	require.False(t, util.IsBot("Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/115.0.0.0", nil))
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tiny-analytics/internal/auth"
+	"tiny-analytics/internal/auth/noncecache"
+	"tiny-analytics/internal/config"
+	"tiny-analytics/internal/model"
+	"tiny-analytics/internal/webhooks"
+)
+
+func TestDispatcherDeliversToMatchingSubscriber(t *testing.T) {
+	var received int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		require.Equal(t, "pageview", r.Header.Get("X-TA-Event"))
+		require.NotEmpty(t, r.Header.Get("X-TA-Delivery"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sites := map[string]config.SiteCredential{
+		"site-1": {
+			Webhooks: []config.Webhook{
+				{URL: srv.URL, EventNames: []string{"pageview"}, MaxRetries: 1},
+				{URL: srv.URL, EventNames: []string{"signup"}, MaxRetries: 1},
+			},
+		},
+	}
+	dispatcher := webhooks.New(sites, nil)
+	evt := model.EnrichedEvent{SiteID: "site-1", EventName: "pageview"}
+
+	dispatcher.Dispatch(context.Background(), evt, []byte(`{"event_name":"pageview"}`))
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&received) == 1
+	}, time.Second, 10*time.Millisecond, "expected exactly the matching subscriber to receive the event")
+}
+
+// TestDispatcherRetryUsesFreshNoncePerAttempt exercises a subscriber that
+// applies the same (siteID, nonce) replay check the ingest API does. The
+// first attempt fails with a non-2xx response *after* the subscriber has
+// already recorded its nonce as seen; if the dispatcher reused that nonce
+// on retry, the subscriber's own replay check would reject it and the
+// delivery would never succeed.
+func TestDispatcherRetryUsesFreshNoncePerAttempt(t *testing.T) {
+	const secret = "whsec_test"
+	nonces := noncecache.New(time.Minute, 1000, time.Minute)
+	var attempts int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		ts, err := strconv.ParseInt(r.Header.Get("X-TA-Timestamp"), 10, 64)
+		require.NoError(t, err)
+
+		err = auth.VerifySigned(secret, "site-1", body, ts, r.Header.Get("X-TA-Nonce"),
+			r.Header.Get("X-TA-Signature"), time.Now(), time.Minute, nonces)
+		require.NoError(t, err, "every attempt must carry a nonce the subscriber hasn't seen before")
+
+		n := atomic.AddInt32(&attempts, 1)
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sites := map[string]config.SiteCredential{
+		"site-1": {
+			Webhooks: []config.Webhook{
+				{URL: srv.URL, Secret: secret, MaxRetries: 2},
+			},
+		},
+	}
+	dispatcher := webhooks.New(sites, nil)
+	evt := model.EnrichedEvent{SiteID: "site-1", EventName: "pageview"}
+
+	dispatcher.Dispatch(context.Background(), evt, []byte(`{"event_name":"pageview"}`))
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&attempts) == 2
+	}, 3*time.Second, 10*time.Millisecond, "expected the retry to succeed once it used a fresh nonce")
+}